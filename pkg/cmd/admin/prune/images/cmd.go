@@ -0,0 +1,101 @@
+package images
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/docker/distribution/digest"
+	"github.com/spf13/cobra"
+
+	imageapi "github.com/openshift/origin/pkg/image/api"
+)
+
+// ImagePruneSource supplies everything ImageOptions.Run needs once `oc adm prune images`'s image-stream
+// listing, tag-revision/age retention, and registry-client wiring - all outside this package - have done
+// their part: the batch of images under consideration, a way to fetch a child manifest by digest while
+// walking a manifest list or OCI image index, a way to correct an Image's stored DockerImageManifestReferences
+// when it disagrees with what the image's own manifest blob says, and a way to actually delete the signatures
+// a Pruner decides to prune.
+type ImagePruneSource interface {
+	PrunableImages() ([]PrunableImage, error)
+	FetchManifest(dgst digest.Digest) (mediaType string, blob []byte, err error)
+	UpdateManifestReferences(dgst digest.Digest, refs []imageapi.ImageReference) error
+	DeleteSignatures(digests []digest.Digest) error
+}
+
+// ImageOptions holds the options for the `oc adm prune images` command.
+type ImageOptions struct {
+	Out    io.Writer
+	Source ImagePruneSource
+
+	Confirm        bool
+	SignaturesMode PruneSignaturesMode
+}
+
+// NewCmdPruneImages returns the `oc adm prune images` command. newSource is called once Run executes, so
+// constructing the real ImagePruneSource - which needs a live image-stream and registry client - can be
+// deferred until flags have been parsed.
+func NewCmdPruneImages(out io.Writer, newSource func() (ImagePruneSource, error)) *cobra.Command {
+	opts := &ImageOptions{Out: out}
+
+	cmd := &cobra.Command{
+		Use:   "images",
+		Short: "Remove signatures of unreferenced images",
+		Long:  "Remove, depending on --prune-signatures, the signatures of images that are pruned or whose signatures are orphaned, and correct stored manifest-list references. Images, manifests and blobs themselves are not deleted; the command reports how many blobs remain referenced.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			source, err := newSource()
+			if err != nil {
+				return err
+			}
+			opts.Source = source
+			return opts.Run()
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.Confirm, "confirm", false, "If true, actually perform the prune operation; by default the command only prints what would be removed.")
+	opts.SignaturesMode.AddFlag(cmd.Flags())
+
+	return cmd
+}
+
+// Run asks o.Source for the current batch of prunable images, uses a Pruner built from o.SignaturesMode to
+// correct any image whose stored DockerImageManifestReferences disagrees with its own manifest blob, count
+// the blobs the images keep referenced once a manifest list's or OCI image index's children are walked
+// (written to o.Out; no blob is deleted), and decide which signatures to remove, then - when o.Confirm is
+// set - asks o.Source to apply both the manifest-reference corrections and the signature deletions.
+func (o *ImageOptions) Run() error {
+	images, err := o.Source.PrunableImages()
+	if err != nil {
+		return err
+	}
+
+	pruner := &Pruner{SignaturesMode: o.SignaturesMode}
+
+	fixes, err := pruner.ManifestReferencesToFix(images)
+	if err != nil {
+		return err
+	}
+	if len(fixes) > 0 && o.Confirm {
+		for _, fix := range fixes {
+			if err := o.Source.UpdateManifestReferences(fix.Digest, fix.Want); err != nil {
+				return err
+			}
+		}
+	}
+
+	blobs, err := pruner.ReferencedBlobs(images, o.Source.FetchManifest)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "%d blob(s) remain referenced once manifest-list and OCI image index children are walked.\n", len(blobs))
+
+	toPrune := pruner.SignaturesToPrune(images)
+	if !o.Confirm {
+		fmt.Fprintf(o.Out, "Dry run enabled - no modifications will be made. Add --confirm to remove %d image signature(s).\n", len(toPrune))
+		return nil
+	}
+	if len(toPrune) == 0 {
+		return nil
+	}
+	return o.Source.DeleteSignatures(toPrune)
+}
@@ -0,0 +1,179 @@
+package images
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/distribution/manifest/schema2"
+
+	imageapi "github.com/openshift/origin/pkg/image/api"
+)
+
+type fakeImagePruneSource struct {
+	images            []PrunableImage
+	fetchManifest     func(dgst digest.Digest) (string, []byte, error)
+	updatedReferences map[digest.Digest][]imageapi.ImageReference
+	deletedSignatures []digest.Digest
+}
+
+func (f *fakeImagePruneSource) PrunableImages() ([]PrunableImage, error) {
+	return f.images, nil
+}
+
+func (f *fakeImagePruneSource) FetchManifest(dgst digest.Digest) (string, []byte, error) {
+	if f.fetchManifest != nil {
+		return f.fetchManifest(dgst)
+	}
+	return "", nil, nil
+}
+
+func (f *fakeImagePruneSource) UpdateManifestReferences(dgst digest.Digest, refs []imageapi.ImageReference) error {
+	if f.updatedReferences == nil {
+		f.updatedReferences = make(map[digest.Digest][]imageapi.ImageReference)
+	}
+	f.updatedReferences[dgst] = refs
+	return nil
+}
+
+func (f *fakeImagePruneSource) DeleteSignatures(digests []digest.Digest) error {
+	f.deletedSignatures = digests
+	return nil
+}
+
+func TestNewCmdPruneImagesRegistersPruneSignaturesFlag(t *testing.T) {
+	cmd := NewCmdPruneImages(&bytes.Buffer{}, func() (ImagePruneSource, error) {
+		return &fakeImagePruneSource{}, nil
+	})
+
+	flag := cmd.Flags().Lookup("prune-signatures")
+	if flag == nil {
+		t.Fatal("expected --prune-signatures to be registered on the command")
+	}
+	if flag.DefValue != string(DefaultPruneSignaturesMode) {
+		t.Errorf("expected --prune-signatures to default to %q, got %q", DefaultPruneSignaturesMode, flag.DefValue)
+	}
+}
+
+func TestImageOptionsRunDryRunDoesNotDelete(t *testing.T) {
+	orphaned := digest.Digest("sha256:0000000000000000000000000000000000000000000000000000000000000001")
+	source := &fakeImagePruneSource{images: []PrunableImage{{Digest: orphaned, Orphaned: true, MediaType: schema2.MediaTypeManifest, Manifest: schema2ManifestBlob(t)}}}
+
+	o := &ImageOptions{Out: &bytes.Buffer{}, Source: source, SignaturesMode: PruneSignaturesAlways}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if source.deletedSignatures != nil {
+		t.Errorf("expected no signatures to be deleted without --confirm, got %v", source.deletedSignatures)
+	}
+}
+
+func TestImageOptionsRunConfirmDeletesPrunedSignatures(t *testing.T) {
+	orphaned := digest.Digest("sha256:0000000000000000000000000000000000000000000000000000000000000001")
+	kept := digest.Digest("sha256:0000000000000000000000000000000000000000000000000000000000000002")
+	source := &fakeImagePruneSource{images: []PrunableImage{
+		{Digest: orphaned, Orphaned: true, MediaType: schema2.MediaTypeManifest, Manifest: schema2ManifestBlob(t)},
+		{Digest: kept, MediaType: schema2.MediaTypeManifest, Manifest: schema2ManifestBlob(t)},
+	}}
+
+	o := &ImageOptions{Out: &bytes.Buffer{}, Source: source, Confirm: true, SignaturesMode: PruneSignaturesOrphansOnly}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(source.deletedSignatures) != 1 || source.deletedSignatures[0] != orphaned {
+		t.Errorf("expected only %s to be deleted, got %v", orphaned, source.deletedSignatures)
+	}
+}
+
+// TestImageOptionsRunWalksManifestListChildren proves that ImageOptions.Run, the production entry point
+// NewCmdPruneImages wires --prune-signatures into, actually drives the manifest-list/OCI-image-index walker
+// in manifest.go rather than leaving it reachable only from manifest_test.go: it feeds Run a manifest list
+// whose child has to be fetched through o.Source.FetchManifest, and checks the child's own config/layer blobs
+// made it into the referenced-blob count Run reports.
+func TestImageOptionsRunWalksManifestListChildren(t *testing.T) {
+	fetched := 0
+	source := &fakeImagePruneSource{
+		images: []PrunableImage{{
+			Digest:    digest.Digest("sha256:0000000000000000000000000000000000000000000000000000000000000010"),
+			MediaType: manifestlist.MediaTypeManifestList,
+			Manifest:  manifestListBlob(t),
+		}},
+		fetchManifest: func(dgst digest.Digest) (string, []byte, error) {
+			if dgst != childDigest {
+				t.Fatalf("unexpected fetch of %s", dgst)
+			}
+			fetched++
+			return schema2.MediaTypeManifest, schema2ManifestBlob(t), nil
+		},
+	}
+
+	var out bytes.Buffer
+	o := &ImageOptions{Out: &out, Source: source}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fetched != 1 {
+		t.Fatalf("expected Run to fetch the manifest list's child exactly once, got %d", fetched)
+	}
+	want := fmt.Sprintf("%d blob(s) remain referenced", 3) // child, child's config, child's layer
+	if got := out.String(); !strings.Contains(got, want) {
+		t.Errorf("expected output to contain %q, got %q", want, got)
+	}
+}
+
+// TestImageOptionsRunFixesStaleManifestReferences proves that ImageOptions.Run, not just
+// imageapi.ManifestListReferences in isolation, is what notices and corrects a manifest-list image whose
+// stored DockerImageManifestReferences never got populated (or drifted) at import time.
+func TestImageOptionsRunFixesStaleManifestReferences(t *testing.T) {
+	listDigest := digest.Digest("sha256:0000000000000000000000000000000000000000000000000000000000000010")
+	source := &fakeImagePruneSource{
+		images: []PrunableImage{{
+			Digest:    listDigest,
+			MediaType: manifestlist.MediaTypeManifestList,
+			Manifest:  manifestListBlob(t),
+		}},
+		fetchManifest: func(dgst digest.Digest) (string, []byte, error) {
+			return schema2.MediaTypeManifest, schema2ManifestBlob(t), nil
+		},
+	}
+
+	o := &ImageOptions{Out: &bytes.Buffer{}, Source: source, Confirm: true}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	refs, ok := source.updatedReferences[listDigest]
+	if !ok {
+		t.Fatalf("expected Run to call UpdateManifestReferences for %s", listDigest)
+	}
+	if len(refs) != 1 || refs[0].Name != childDigest.String() || refs[0].MediaType != schema2.MediaTypeManifest {
+		t.Errorf("UpdateManifestReferences(%s, ...) got %+v", listDigest, refs)
+	}
+}
+
+// TestImageOptionsRunDryRunDoesNotFixManifestReferences proves the manifest-reference correction, like
+// signature deletion, is a modification that --confirm gates rather than something Run applies unconditionally.
+func TestImageOptionsRunDryRunDoesNotFixManifestReferences(t *testing.T) {
+	listDigest := digest.Digest("sha256:0000000000000000000000000000000000000000000000000000000000000010")
+	source := &fakeImagePruneSource{
+		images: []PrunableImage{{
+			Digest:    listDigest,
+			MediaType: manifestlist.MediaTypeManifestList,
+			Manifest:  manifestListBlob(t),
+		}},
+		fetchManifest: func(dgst digest.Digest) (string, []byte, error) {
+			return schema2.MediaTypeManifest, schema2ManifestBlob(t), nil
+		},
+	}
+
+	o := &ImageOptions{Out: &bytes.Buffer{}, Source: source}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if source.updatedReferences != nil {
+		t.Errorf("expected no manifest-reference updates without --confirm, got %v", source.updatedReferences)
+	}
+}
@@ -0,0 +1,139 @@
+package images
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/distribution/manifest/schema1"
+	"github.com/docker/distribution/manifest/schema2"
+	imagespecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// ManifestDescriptors is everything the pruner needs to know about a single manifest: the blobs it
+// references directly, and, for manifest lists and OCI image indexes, the child manifests it fans out to.
+type ManifestDescriptors struct {
+	Config   *distribution.Descriptor
+	Layers   []distribution.Descriptor
+	Children []distribution.Descriptor
+}
+
+// DescriptorsForManifest parses a manifest blob according to its declared media type and returns the
+// blobs it references directly (and, for manifest lists and OCI image indexes, the child manifests it
+// fans out to). It recognizes schema1, schema2, Docker manifest lists, OCI image manifests and OCI image
+// indexes; any other media type is reported as unsupported so the pruner fails loudly instead of silently
+// under-counting referenced blobs.
+func DescriptorsForManifest(mediaType string, blob []byte) (ManifestDescriptors, error) {
+	switch mediaType {
+	case schema1.MediaTypeManifest, schema1.MediaTypeSignedManifest:
+		var m schema1.Manifest
+		if err := json.Unmarshal(blob, &m); err != nil {
+			return ManifestDescriptors{}, err
+		}
+		layers := make([]distribution.Descriptor, 0, len(m.FSLayers))
+		for _, l := range m.FSLayers {
+			layers = append(layers, distribution.Descriptor{Digest: l.BlobSum})
+		}
+		return ManifestDescriptors{Layers: layers}, nil
+
+	case schema2.MediaTypeManifest:
+		var m schema2.DeserializedManifest
+		if err := json.Unmarshal(blob, &m); err != nil {
+			return ManifestDescriptors{}, err
+		}
+		config := m.Config
+		return ManifestDescriptors{Config: &config, Layers: m.Layers}, nil
+
+	case imagespecv1.MediaTypeImageManifest:
+		// schema2.DeserializedManifest.UnmarshalJSON validates mediaType against
+		// schema2.MediaTypeManifest and rejects the OCI media type, so OCI image manifests need their own,
+		// non-validating struct with the same config/layers shape.
+		var m ociImageManifest
+		if err := json.Unmarshal(blob, &m); err != nil {
+			return ManifestDescriptors{}, err
+		}
+		config := m.Config
+		return ManifestDescriptors{Config: &config, Layers: m.Layers}, nil
+
+	case manifestlist.MediaTypeManifestList, imagespecv1.MediaTypeImageIndex:
+		var l manifestlist.DeserializedManifestList
+		if err := json.Unmarshal(blob, &l); err != nil {
+			return ManifestDescriptors{}, err
+		}
+		children := make([]distribution.Descriptor, 0, len(l.Manifests))
+		for _, m := range l.Manifests {
+			children = append(children, m.Descriptor)
+		}
+		return ManifestDescriptors{Children: children}, nil
+
+	default:
+		return ManifestDescriptors{}, fmt.Errorf("unsupported manifest media type %q", mediaType)
+	}
+}
+
+// ociImageManifest mirrors the config/layers shape of an OCI image manifest closely enough for
+// DescriptorsForManifest's purposes, without schema2.DeserializedManifest's mediaType validation.
+type ociImageManifest struct {
+	Config distribution.Descriptor   `json:"config"`
+	Layers []distribution.Descriptor `json:"layers"`
+}
+
+// IsManifestList reports whether mediaType identifies a multi-manifest node (a Docker manifest list or an
+// OCI image index) that the pruner must recurse into rather than treat as a leaf image.
+func IsManifestList(mediaType string) bool {
+	return mediaType == manifestlist.MediaTypeManifestList || mediaType == imagespecv1.MediaTypeImageIndex
+}
+
+// ReferencedBlobs returns the full set of blob digests (configs and layers) that the manifest identified by
+// mediaType/blob keeps alive. For a manifest list or OCI image index it recurses into every child manifest,
+// fetched on demand via fetchManifest, so none of a multi-arch image's children are mistaken for orphans
+// during a prune run.
+func ReferencedBlobs(mediaType string, blob []byte, fetchManifest func(dgst digest.Digest) (childMediaType string, childBlob []byte, err error)) (map[digest.Digest]struct{}, error) {
+	return referencedBlobs(mediaType, blob, fetchManifest, map[digest.Digest]struct{}{})
+}
+
+func referencedBlobs(mediaType string, blob []byte, fetchManifest func(dgst digest.Digest) (childMediaType string, childBlob []byte, err error), visited map[digest.Digest]struct{}) (map[digest.Digest]struct{}, error) {
+	descriptors, err := DescriptorsForManifest(mediaType, blob)
+	if err != nil {
+		return nil, err
+	}
+
+	blobs := make(map[digest.Digest]struct{})
+	if descriptors.Config != nil {
+		blobs[descriptors.Config.Digest] = struct{}{}
+	}
+	for _, l := range descriptors.Layers {
+		blobs[l.Digest] = struct{}{}
+	}
+
+	if !IsManifestList(mediaType) {
+		return blobs, nil
+	}
+
+	for _, child := range descriptors.Children {
+		blobs[child.Digest] = struct{}{}
+
+		if _, seen := visited[child.Digest]; seen {
+			// A list/index whose children form a cycle (or repeat an ancestor) is malformed; skip
+			// re-descending into it rather than recursing forever.
+			continue
+		}
+		visited[child.Digest] = struct{}{}
+
+		childMediaType, childBlob, err := fetchManifest(child.Digest)
+		if err != nil {
+			return nil, err
+		}
+		childBlobs, err := referencedBlobs(childMediaType, childBlob, fetchManifest, visited)
+		if err != nil {
+			return nil, err
+		}
+		for d := range childBlobs {
+			blobs[d] = struct{}{}
+		}
+	}
+
+	return blobs, nil
+}
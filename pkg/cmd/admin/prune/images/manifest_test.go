@@ -0,0 +1,154 @@
+package images
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/distribution/manifest/schema2"
+	imagespecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const (
+	configDigest = digest.Digest("sha256:0000000000000000000000000000000000000000000000000000000000000001")
+	layerDigest  = digest.Digest("sha256:0000000000000000000000000000000000000000000000000000000000000002")
+	childDigest  = digest.Digest("sha256:0000000000000000000000000000000000000000000000000000000000000003")
+)
+
+func schema2ManifestBlob(t *testing.T) []byte {
+	blob, err := json.Marshal(schema2.Manifest{
+		Versioned: manifest.Versioned{SchemaVersion: 2, MediaType: schema2.MediaTypeManifest},
+		Config:    distribution.Descriptor{MediaType: "application/vnd.docker.container.image.v1+json", Digest: configDigest, Size: 100},
+		Layers:    []distribution.Descriptor{{MediaType: schema2.MediaTypeLayer, Digest: layerDigest, Size: 200}},
+	})
+	if err != nil {
+		t.Fatalf("failed to build schema2 manifest: %v", err)
+	}
+	return blob
+}
+
+func ociImageManifestBlob(t *testing.T) []byte {
+	blob, err := json.Marshal(ociImageManifest{
+		Config: distribution.Descriptor{MediaType: "application/vnd.oci.image.config.v1+json", Digest: configDigest, Size: 100},
+		Layers: []distribution.Descriptor{{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Digest: layerDigest, Size: 200}},
+	})
+	if err != nil {
+		t.Fatalf("failed to build OCI image manifest: %v", err)
+	}
+	return blob
+}
+
+func TestDescriptorsForManifest_OCIImageManifest(t *testing.T) {
+	descriptors, err := DescriptorsForManifest(imagespecv1.MediaTypeImageManifest, ociImageManifestBlob(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if descriptors.Config == nil || descriptors.Config.Digest != configDigest {
+		t.Fatalf("expected config digest %s, got %+v", configDigest, descriptors.Config)
+	}
+	if len(descriptors.Layers) != 1 || descriptors.Layers[0].Digest != layerDigest {
+		t.Fatalf("expected single layer %s, got %+v", layerDigest, descriptors.Layers)
+	}
+}
+
+func ociImageIndexBlob(t *testing.T) []byte {
+	blob, err := json.Marshal(manifestlist.ManifestList{
+		Versioned: manifest.Versioned{SchemaVersion: 2, MediaType: imagespecv1.MediaTypeImageIndex},
+		Manifests: []manifestlist.ManifestDescriptor{
+			{
+				Descriptor: distribution.Descriptor{MediaType: imagespecv1.MediaTypeImageManifest, Digest: childDigest, Size: 300},
+				Platform:   manifestlist.PlatformSpec{Architecture: "amd64", OS: "linux"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to build OCI image index: %v", err)
+	}
+	return blob
+}
+
+func TestDescriptorsForManifest_OCIImageIndex(t *testing.T) {
+	descriptors, err := DescriptorsForManifest(imagespecv1.MediaTypeImageIndex, ociImageIndexBlob(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(descriptors.Children) != 1 || descriptors.Children[0].Digest != childDigest {
+		t.Fatalf("expected single child %s, got %+v", childDigest, descriptors.Children)
+	}
+}
+
+func TestDescriptorsForManifest_Schema2(t *testing.T) {
+	descriptors, err := DescriptorsForManifest(schema2.MediaTypeManifest, schema2ManifestBlob(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if descriptors.Config == nil || descriptors.Config.Digest != configDigest {
+		t.Fatalf("expected config digest %s, got %+v", configDigest, descriptors.Config)
+	}
+	if len(descriptors.Layers) != 1 || descriptors.Layers[0].Digest != layerDigest {
+		t.Fatalf("expected single layer %s, got %+v", layerDigest, descriptors.Layers)
+	}
+}
+
+func manifestListBlob(t *testing.T) []byte {
+	blob, err := json.Marshal(manifestlist.ManifestList{
+		Versioned: manifest.Versioned{SchemaVersion: 2, MediaType: manifestlist.MediaTypeManifestList},
+		Manifests: []manifestlist.ManifestDescriptor{
+			{
+				Descriptor: distribution.Descriptor{MediaType: schema2.MediaTypeManifest, Digest: childDigest, Size: 300},
+				Platform:   manifestlist.PlatformSpec{Architecture: "amd64", OS: "linux"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to build manifest list: %v", err)
+	}
+	return blob
+}
+
+func TestDescriptorsForManifest_ManifestList(t *testing.T) {
+	descriptors, err := DescriptorsForManifest(manifestlist.MediaTypeManifestList, manifestListBlob(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(descriptors.Children) != 1 || descriptors.Children[0].Digest != childDigest {
+		t.Fatalf("expected single child %s, got %+v", childDigest, descriptors.Children)
+	}
+}
+
+func TestIsManifestList(t *testing.T) {
+	cases := map[string]bool{
+		schema2.MediaTypeManifest:          false,
+		imagespecv1.MediaTypeImageManifest: false,
+		manifestlist.MediaTypeManifestList: true,
+		imagespecv1.MediaTypeImageIndex:    true,
+	}
+	for mediaType, want := range cases {
+		if got := IsManifestList(mediaType); got != want {
+			t.Errorf("IsManifestList(%q) = %v, want %v", mediaType, got, want)
+		}
+	}
+}
+
+func TestReferencedBlobs_RecursesIntoChildren(t *testing.T) {
+	fetchManifest := func(dgst digest.Digest) (string, []byte, error) {
+		if dgst != childDigest {
+			t.Fatalf("unexpected fetch of %s", dgst)
+		}
+		return schema2.MediaTypeManifest, schema2ManifestBlob(t), nil
+	}
+
+	blobs, err := ReferencedBlobs(manifestlist.MediaTypeManifestList, manifestListBlob(t), fetchManifest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, want := range []digest.Digest{childDigest, configDigest, layerDigest} {
+		if _, ok := blobs[want]; !ok {
+			t.Errorf("expected %s to be in the referenced blob set, got %+v", want, blobs)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package images
+
+import (
+	"reflect"
+
+	"github.com/docker/distribution/digest"
+
+	imageapi "github.com/openshift/origin/pkg/image/api"
+)
+
+// PrunableImage is the minimal view of a single image a Pruner needs once the ImagePruneSource handed to
+// NewCmdPruneImages (which, outside this package, owns image-stream listing, tag-revision/age retention and
+// the registry client) has already decided whether the image itself is being pruned and whether its
+// signature has been orphaned by a revoked verification.
+type PrunableImage struct {
+	Digest    digest.Digest
+	MediaType string
+	Manifest  []byte
+	Pruned    bool
+	Orphaned  bool
+
+	// ManifestReferences is img's own Image.DockerImageManifestReferences, as last recorded by whatever
+	// imported it. ManifestReferencesToFix recomputes this from MediaType/Manifest to catch an importer that
+	// never populated it (or got it wrong).
+	ManifestReferences []imageapi.ImageReference
+}
+
+// ImageManifestReferenceFix describes an image whose stored ManifestReferences doesn't match what its own
+// manifest blob actually contains.
+type ImageManifestReferenceFix struct {
+	Digest digest.Digest
+	Want   []imageapi.ImageReference
+}
+
+// Pruner applies --prune-signatures and manifest-list/OCI-image-index awareness to a batch of already
+// retention-evaluated images. ImageOptions.Run constructs one with the PruneSignaturesMode parsed from the
+// --prune-signatures flag, then uses ReferencedBlobs, rather than each image's own Layers alone, to compute
+// which blobs a multi-arch image's children keep alive. Run only reports the size of that set; it does not
+// decide which blobs are orphaned and deletes no blob.
+type Pruner struct {
+	SignaturesMode PruneSignaturesMode
+}
+
+// SignaturesToPrune returns the digests of the images in batch whose signatures should be removed under
+// p.SignaturesMode.
+func (p *Pruner) SignaturesToPrune(batch []PrunableImage) []digest.Digest {
+	var digests []digest.Digest
+	for _, img := range batch {
+		if ShouldPruneSignature(p.SignaturesMode, img.Pruned, img.Orphaned) {
+			digests = append(digests, img.Digest)
+		}
+	}
+	return digests
+}
+
+// ManifestReferencesToFix recomputes each image's DockerImageManifestReferences from its own MediaType/Manifest
+// via imageapi.ManifestListReferences and returns the ones whose stored ManifestReferences disagrees with that
+// recomputed value - whether because an importer never populated it or populated it incorrectly.
+// When --confirm is set, ImageOptions.Run passes the result to o.Source.UpdateManifestReferences so the
+// stored Image is corrected. ReferencedBlobs does not read the stored ManifestReferences; it walks each
+// image's MediaType/Manifest directly.
+func (p *Pruner) ManifestReferencesToFix(batch []PrunableImage) ([]ImageManifestReferenceFix, error) {
+	var fixes []ImageManifestReferenceFix
+	for _, img := range batch {
+		want, err := imageapi.ManifestListReferences(img.MediaType, img.Manifest)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(want, img.ManifestReferences) {
+			fixes = append(fixes, ImageManifestReferenceFix{Digest: img.Digest, Want: want})
+		}
+	}
+	return fixes, nil
+}
+
+// ReferencedBlobs returns the full set of blob digests kept alive by the manifests in batch, via
+// DescriptorsForManifest/ReferencedBlobs, so a manifest list's or OCI image index's per-platform children are
+// walked into rather than mistaken for orphans. fetchManifest resolves a child digest encountered while
+// walking a list/index to its media type and blob.
+func (p *Pruner) ReferencedBlobs(batch []PrunableImage, fetchManifest func(dgst digest.Digest) (mediaType string, blob []byte, err error)) (map[digest.Digest]struct{}, error) {
+	blobs := make(map[digest.Digest]struct{})
+	for _, img := range batch {
+		imgBlobs, err := ReferencedBlobs(img.MediaType, img.Manifest, fetchManifest)
+		if err != nil {
+			return nil, err
+		}
+		for d := range imgBlobs {
+			blobs[d] = struct{}{}
+		}
+	}
+	return blobs, nil
+}
@@ -0,0 +1,60 @@
+package images
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/distribution/manifest/schema2"
+)
+
+func TestPrunerSignaturesToPrune(t *testing.T) {
+	prunedImage := digest.Digest("sha256:0000000000000000000000000000000000000000000000000000000000000001")
+	orphanedImage := digest.Digest("sha256:0000000000000000000000000000000000000000000000000000000000000002")
+	plainImage := digest.Digest("sha256:0000000000000000000000000000000000000000000000000000000000000003")
+
+	batch := []PrunableImage{
+		{Digest: prunedImage, Pruned: true},
+		{Digest: orphanedImage, Orphaned: true},
+		{Digest: plainImage},
+	}
+
+	p := &Pruner{SignaturesMode: PruneSignaturesAlways}
+	if got, want := p.SignaturesToPrune(batch), []digest.Digest{prunedImage, orphanedImage}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SignaturesToPrune() with mode=%s = %v, want %v", p.SignaturesMode, got, want)
+	}
+
+	p = &Pruner{SignaturesMode: PruneSignaturesOrphansOnly}
+	if got, want := p.SignaturesToPrune(batch), []digest.Digest{orphanedImage}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SignaturesToPrune() with mode=%s = %v, want %v", p.SignaturesMode, got, want)
+	}
+
+	p = &Pruner{SignaturesMode: PruneSignaturesNever}
+	if got := p.SignaturesToPrune(batch); got != nil {
+		t.Errorf("SignaturesToPrune() with mode=%s = %v, want nil", p.SignaturesMode, got)
+	}
+}
+
+func TestPrunerReferencedBlobs(t *testing.T) {
+	fetchManifest := func(dgst digest.Digest) (string, []byte, error) {
+		if dgst != childDigest {
+			t.Fatalf("unexpected fetch of %s", dgst)
+		}
+		return schema2.MediaTypeManifest, schema2ManifestBlob(t), nil
+	}
+
+	p := &Pruner{}
+	batch := []PrunableImage{
+		{Digest: childDigest, MediaType: manifestlist.MediaTypeManifestList, Manifest: manifestListBlob(t)},
+	}
+	blobs, err := p.ReferencedBlobs(batch, fetchManifest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []digest.Digest{childDigest, configDigest, layerDigest} {
+		if _, ok := blobs[want]; !ok {
+			t.Errorf("expected %s to be in the referenced blob set, got %+v", want, blobs)
+		}
+	}
+}
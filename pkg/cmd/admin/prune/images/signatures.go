@@ -0,0 +1,80 @@
+package images
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+)
+
+// PruneSignaturesMode controls which image signatures a `oc adm prune images` run removes.
+type PruneSignaturesMode string
+
+const (
+	// PruneSignaturesNever leaves every signature alone; only images and their layers are pruned.
+	PruneSignaturesNever PruneSignaturesMode = "false"
+	// PruneSignaturesAlways removes the signatures of every image that is itself being pruned, as well as
+	// any signature left orphaned by a revoked verification (e.g. `oc adm verify-image-signature
+	// --remove-all` against an image that is kept).
+	PruneSignaturesAlways PruneSignaturesMode = "true"
+	// PruneSignaturesOrphansOnly leaves the signatures of pruned images alone - they disappear with the
+	// image anyway - but still removes signatures that have been orphaned on an image that is kept.
+	PruneSignaturesOrphansOnly PruneSignaturesMode = "orphans-only"
+)
+
+// DefaultPruneSignaturesMode is used when --prune-signatures is not passed.
+const DefaultPruneSignaturesMode = PruneSignaturesNever
+
+// ParsePruneSignaturesMode validates a --prune-signatures flag value.
+func ParsePruneSignaturesMode(value string) (PruneSignaturesMode, error) {
+	switch PruneSignaturesMode(value) {
+	case PruneSignaturesNever, PruneSignaturesAlways, PruneSignaturesOrphansOnly:
+		return PruneSignaturesMode(value), nil
+	default:
+		return "", fmt.Errorf("invalid --prune-signatures value %q: must be one of %q, %q or %q", value, PruneSignaturesNever, PruneSignaturesAlways, PruneSignaturesOrphansOnly)
+	}
+}
+
+// String implements pflag.Value so PruneSignaturesMode can be used directly as a flag destination.
+func (m *PruneSignaturesMode) String() string {
+	if m == nil || *m == "" {
+		return string(DefaultPruneSignaturesMode)
+	}
+	return string(*m)
+}
+
+// Set implements pflag.Value.
+func (m *PruneSignaturesMode) Set(value string) error {
+	parsed, err := ParsePruneSignaturesMode(value)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Type implements pflag.Value.
+func (m *PruneSignaturesMode) Type() string {
+	return "string"
+}
+
+// AddFlag registers --prune-signatures on fs, bound to m and defaulting to DefaultPruneSignaturesMode.
+// NewCmdPruneImages wires this in alongside --confirm, and ImageOptions.Run passes m to a Pruner, which
+// consults ShouldPruneSignature for each image's signatures.
+func (m *PruneSignaturesMode) AddFlag(fs *pflag.FlagSet) {
+	*m = DefaultPruneSignaturesMode
+	fs.Var(m, "prune-signatures", "Specify whether to prune signatures: true, false, or orphans-only.")
+}
+
+// ShouldPruneSignature reports whether a signature should be removed under mode, given whether the image it
+// belongs to is itself being pruned and whether the signature is orphaned - its image is kept, but the
+// signature's verification has since been revoked.
+func ShouldPruneSignature(mode PruneSignaturesMode, imagePruned, orphaned bool) bool {
+	switch mode {
+	case PruneSignaturesAlways:
+		return imagePruned || orphaned
+	case PruneSignaturesOrphansOnly:
+		return orphaned
+	default:
+		return false
+	}
+}
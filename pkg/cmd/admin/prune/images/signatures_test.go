@@ -0,0 +1,58 @@
+package images
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestParsePruneSignaturesMode(t *testing.T) {
+	for _, value := range []string{"true", "false", "orphans-only"} {
+		if _, err := ParsePruneSignaturesMode(value); err != nil {
+			t.Errorf("ParsePruneSignaturesMode(%q) returned unexpected error: %v", value, err)
+		}
+	}
+	if _, err := ParsePruneSignaturesMode("bogus"); err == nil {
+		t.Error("expected an error for an invalid --prune-signatures value, got none")
+	}
+}
+
+func TestPruneSignaturesModeAddFlag(t *testing.T) {
+	var mode PruneSignaturesMode
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	mode.AddFlag(fs)
+
+	if mode != DefaultPruneSignaturesMode {
+		t.Fatalf("expected AddFlag to default the mode to %q, got %q", DefaultPruneSignaturesMode, mode)
+	}
+	if err := fs.Set("prune-signatures", "orphans-only"); err != nil {
+		t.Fatalf("unexpected error setting --prune-signatures: %v", err)
+	}
+	if mode != PruneSignaturesOrphansOnly {
+		t.Fatalf("expected --prune-signatures=orphans-only to set the mode, got %q", mode)
+	}
+	if err := fs.Set("prune-signatures", "bogus"); err == nil {
+		t.Error("expected an error setting --prune-signatures=bogus, got none")
+	}
+}
+
+func TestShouldPruneSignature(t *testing.T) {
+	tests := []struct {
+		mode        PruneSignaturesMode
+		imagePruned bool
+		orphaned    bool
+		want        bool
+	}{
+		{PruneSignaturesNever, true, true, false},
+		{PruneSignaturesAlways, true, false, true},
+		{PruneSignaturesAlways, false, true, true},
+		{PruneSignaturesAlways, false, false, false},
+		{PruneSignaturesOrphansOnly, true, false, false},
+		{PruneSignaturesOrphansOnly, false, true, true},
+	}
+	for _, tt := range tests {
+		if got := ShouldPruneSignature(tt.mode, tt.imagePruned, tt.orphaned); got != tt.want {
+			t.Errorf("ShouldPruneSignature(%s, imagePruned=%v, orphaned=%v) = %v, want %v", tt.mode, tt.imagePruned, tt.orphaned, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+package server
+
+const (
+	// AcceptSchema2EnvVar is the name of the environment variable that, when set to "true", makes the
+	// registry accept schema2 manifests (and manifest lists) on push in addition to schema1.
+	AcceptSchema2EnvVar = "REGISTRY_MIDDLEWARE_REPOSITORY_OPENSHIFT_ACCEPTSCHEMA2"
+
+	// AcceptOCIEnvVar is the name of the environment variable that, when set to "true", makes the registry
+	// accept OCI image manifests and image indexes in addition to whatever schema1/schema2 support
+	// AcceptSchema2EnvVar already grants it.
+	AcceptOCIEnvVar = "REGISTRY_MIDDLEWARE_REPOSITORY_OPENSHIFT_ACCEPTOCI"
+)
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"encoding/json"
+
+	"github.com/docker/distribution/manifest/manifestlist"
+	imagespecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// IsManifestList reports whether mediaType identifies a multi-manifest node - a Docker manifest list or an
+// OCI image index - whose children belong in DockerImageManifestReferences, rather than a leaf manifest with
+// its own layers.
+func IsManifestList(mediaType string) bool {
+	return mediaType == manifestlist.MediaTypeManifestList || mediaType == imagespecv1.MediaTypeImageIndex
+}
+
+// ManifestListReferences parses a manifest list or OCI image index blob and returns the ImageReferences that
+// belong on Image.DockerImageManifestReferences: what the registry is expected to compute and record on an
+// Image at import time - on push, or the first time a pullthrough resolves it - so any client reading the
+// Image can see which per-platform children it fans out to. The `oc adm prune images` walker recognizes
+// lists by media type and uses this function to correct stored references that disagree with the blob.
+// It returns (nil, nil) for any mediaType that isn't a manifest list/index.
+func ManifestListReferences(mediaType string, blob []byte) ([]ImageReference, error) {
+	if !IsManifestList(mediaType) {
+		return nil, nil
+	}
+
+	var l manifestlist.DeserializedManifestList
+	if err := json.Unmarshal(blob, &l); err != nil {
+		return nil, err
+	}
+
+	refs := make([]ImageReference, 0, len(l.Manifests))
+	for _, m := range l.Manifests {
+		refs = append(refs, ImageReference{Name: m.Digest.String(), MediaType: m.MediaType})
+	}
+	return refs, nil
+}
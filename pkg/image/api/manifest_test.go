@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/distribution/manifest/schema2"
+	imagespecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const childDigest = digest.Digest("sha256:0000000000000000000000000000000000000000000000000000000000000001")
+
+func manifestListBlob(t *testing.T, mediaType string) []byte {
+	blob, err := json.Marshal(manifestlist.ManifestList{
+		Versioned: manifest.Versioned{SchemaVersion: 2, MediaType: mediaType},
+		Manifests: []manifestlist.ManifestDescriptor{
+			{
+				Descriptor: distribution.Descriptor{MediaType: schema2.MediaTypeManifest, Digest: childDigest, Size: 300},
+				Platform:   manifestlist.PlatformSpec{Architecture: "amd64", OS: "linux"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to build manifest list: %v", err)
+	}
+	return blob
+}
+
+func TestManifestListReferences(t *testing.T) {
+	for _, mediaType := range []string{manifestlist.MediaTypeManifestList, imagespecv1.MediaTypeImageIndex} {
+		refs, err := ManifestListReferences(mediaType, manifestListBlob(t, mediaType))
+		if err != nil {
+			t.Fatalf("ManifestListReferences(%q) returned unexpected error: %v", mediaType, err)
+		}
+		if len(refs) != 1 || refs[0].Name != childDigest.String() || refs[0].MediaType != schema2.MediaTypeManifest {
+			t.Errorf("ManifestListReferences(%q) = %+v, want a single reference to %s", mediaType, refs, childDigest)
+		}
+	}
+}
+
+func TestManifestListReferencesNonList(t *testing.T) {
+	refs, err := ManifestListReferences(schema2.MediaTypeManifest, []byte(`{}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if refs != nil {
+		t.Errorf("expected nil references for a non-list media type, got %+v", refs)
+	}
+}
+
+func TestIsManifestList(t *testing.T) {
+	cases := map[string]bool{
+		schema2.MediaTypeManifest:          false,
+		imagespecv1.MediaTypeImageManifest: false,
+		manifestlist.MediaTypeManifestList: true,
+		imagespecv1.MediaTypeImageIndex:    true,
+	}
+	for mediaType, want := range cases {
+		if got := IsManifestList(mediaType); got != want {
+			t.Errorf("IsManifestList(%q) = %v, want %v", mediaType, got, want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DockerImageMetadata holds the subset of a container image's config/history that the platform surfaces
+// about an Image, normally populated by the registry when the image is pushed or imported.
+type DockerImageMetadata struct {
+	ID   string
+	Size int64
+}
+
+// ImageLayer represents a single layer of the image referenced by a container image manifest.
+type ImageLayer struct {
+	Name      string
+	LayerSize int64
+	MediaType string
+}
+
+// ImageSignature is a single detached signature of an image, as stored by the registry's signature store.
+type ImageSignature struct {
+	Name string
+}
+
+// ImageReference is a minimal pointer to another Image, used to record the children of a manifest list or
+// OCI image index without duplicating their full metadata.
+type ImageReference struct {
+	// Name is the digest-based name of the referenced Image.
+	Name string
+	// MediaType is the manifest media type of the referenced Image.
+	MediaType string
+}
+
+// Image is a container image and the metadata about it necessary for the platform to manage its lifecycle.
+type Image struct {
+	metav1.TypeMeta
+	metav1.ObjectMeta
+
+	DockerImageReference        string
+	DockerImageMetadata         DockerImageMetadata
+	DockerImageManifestMediaType string
+	DockerImageLayers           []ImageLayer
+	Signatures                  []ImageSignature
+
+	// DockerImageManifestReferences holds the children of a manifest list or OCI image index manifest, as
+	// computed by ManifestListReferences; it is empty for a leaf image with its own layers. The prune walker
+	// in pkg/cmd/admin/prune/images identifies list/index images by DockerImageManifestMediaType, not by this
+	// field, and recomputes the field from the manifest blob to correct a stored value that disagrees.
+	DockerImageManifestReferences []ImageReference
+}
+
+// ImageStreamTag represents an Image resolved through an ImageStream's tag.
+type ImageStreamTag struct {
+	metav1.TypeMeta
+	metav1.ObjectMeta
+
+	Image Image
+}
+
+// SplitImageStreamTag turns the name of an ImageStreamTag into its image stream name and tag components.
+// nameAndTag is expected to be of the form "<imagestream>:<tag>"; ok is false if it isn't.
+func SplitImageStreamTag(nameAndTag string) (name string, tag string, ok bool) {
+	parts := strings.SplitN(nameAndTag, ":", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// String returns the referenced image's digest-based name followed by its media type in parentheses.
+func (i ImageReference) String() string {
+	return fmt.Sprintf("%s (%s)", i.Name, i.MediaType)
+}
@@ -0,0 +1,135 @@
+package images
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+
+	"github.com/docker/distribution/digest"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	exutil "github.com/openshift/origin/test/extended/util"
+)
+
+// BuildAndPushImageOfSizeWithDocker builds a local image with dClient - a busybox base plus `layers` RUN
+// layers, each adding `size` random bytes - and pushes it to isName:tag on the registry instance backing
+// scope. When cleanup is true the local image is removed once the build has succeeded, before the function
+// returns and whether or not the push succeeds, so repeated calls within a single spec don't fill up the
+// test host's Docker storage.
+func BuildAndPushImageOfSizeWithDocker(oc *exutil.CLI, scope *exutil.RegistryScope, dClient *docker.Client, isName, tag string, size, layers int, outSink io.Writer, cleanup bool) (string, error) {
+	pushSpec := scope.PushSpec(oc.Namespace(), isName, tag)
+
+	var dockerfile bytes.Buffer
+	fmt.Fprintln(&dockerfile, "FROM busybox")
+	for i := 0; i < layers; i++ {
+		fmt.Fprintf(&dockerfile, "RUN head -c %d /dev/urandom > /layer-%d\n", size, i)
+	}
+	buildContext, err := tarOfFiles(map[string][]byte{"Dockerfile": dockerfile.Bytes()})
+	if err != nil {
+		return "", err
+	}
+
+	if err := dClient.BuildImage(docker.BuildImageOptions{
+		Name:         pushSpec,
+		InputStream:  buildContext,
+		OutputStream: outSink,
+	}); err != nil {
+		return "", fmt.Errorf("docker build failed: %v", err)
+	}
+	if cleanup {
+		defer dClient.RemoveImage(pushSpec)
+	}
+
+	if err := dClient.PushImage(docker.PushImageOptions{Name: pushSpec, OutputStream: outSink}, docker.AuthConfiguration{}); err != nil {
+		return "", fmt.Errorf("docker push of %s failed: %v", pushSpec, err)
+	}
+
+	if err := exutil.WaitForAnImageStreamTag(oc, oc.Namespace(), isName, tag); err != nil {
+		return "", err
+	}
+	istag, err := oc.Client().ImageStreamTags(oc.Namespace()).Get(isName, tag)
+	if err != nil {
+		return "", err
+	}
+	return istag.Image.Name, nil
+}
+
+// tarOfFiles packs files into an in-memory tar stream suitable for use as a Docker build context.
+func tarOfFiles(files map[string][]byte) (io.Reader, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(content); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
+
+// MirrorBlobInRegistry makes the blob identified by dgst resolvable from the registry instance backing
+// scope without it having been pushed there directly: it issues blob pulls against repoName through that
+// registry's pullthrough proxy (which transparently fetches and caches blobs belonging to images already
+// imported into the image stream) until the blob shows up in the registry's own content-addressable blob
+// store, or timeout elapses. Unlike a real push, this never links the blob into repoName's local
+// _layers directory - it only seeds the registry's global blob store.
+func MirrorBlobInRegistry(oc *exutil.CLI, scope *exutil.RegistryScope, dgst digest.Digest, repoName string, timeout time.Duration) error {
+	ns := oc.Namespace()
+	admin := oc.SetNamespace(metav1.NamespaceDefault).AsAdmin()
+	defer oc.SetNamespace(ns)
+
+	token, err := oc.Run("whoami").Args("-t").Output()
+	if err != nil {
+		return fmt.Errorf("failed to obtain a bearer token: %v", err)
+	}
+	token = strings.TrimSpace(token)
+
+	url := fmt.Sprintf("https://localhost:5000/v2/%s/blobs/%s", repoName, dgst)
+	return wait.PollImmediate(time.Second, timeout, func() (bool, error) {
+		output, err := admin.Run("rsh").Args(
+			scope.DeploymentConfigRef(), "curl", "-sk", "-o", "/dev/null", "-w", "%{http_code}",
+			"-H", fmt.Sprintf("Authorization: Bearer %s", token), url,
+		).Output()
+		if err != nil || strings.TrimSpace(output) != "200" {
+			return false, nil
+		}
+		globally, _, err := IsBlobStoredInRegistry(oc, scope, dgst, repoName)
+		if err != nil {
+			return false, err
+		}
+		return globally, nil
+	})
+}
+
+// IsBlobStoredInRegistry reports whether the blob identified by dgst is present in the registry's global
+// content-addressable blob store (globally) and whether it is linked into repoName's own _layers directory
+// (inRepository) on the registry instance backing scope.
+func IsBlobStoredInRegistry(oc *exutil.CLI, scope *exutil.RegistryScope, dgst digest.Digest, repoName string) (globally, inRepository bool, err error) {
+	ns := oc.Namespace()
+	admin := oc.SetNamespace(metav1.NamespaceDefault).AsAdmin()
+	defer oc.SetNamespace(ns)
+
+	blobPath := fmt.Sprintf("/registry/docker/registry/v2/blobs/%s/%s/%s/data", dgst.Algorithm(), dgst.Hex()[:2], dgst.Hex())
+	if _, lsErr := admin.Run("rsh").Args(scope.DeploymentConfigRef(), "ls", blobPath).Output(); lsErr == nil {
+		globally = true
+	}
+
+	layerPath := fmt.Sprintf("/registry/docker/registry/v2/repositories/%s/_layers/%s/%s/link", repoName, dgst.Algorithm(), dgst.Hex())
+	if _, lsErr := admin.Run("rsh").Args(scope.DeploymentConfigRef(), "ls", layerPath).Output(); lsErr == nil {
+		inRepository = true
+	}
+
+	return globally, inRepository, nil
+}
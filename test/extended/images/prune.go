@@ -2,6 +2,9 @@ package images
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"os/exec"
 	"regexp"
 	"sort"
 	"strconv"
@@ -12,12 +15,13 @@ import (
 	o "github.com/onsi/gomega"
 
 	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest/manifestlist"
 	"github.com/docker/distribution/manifest/schema1"
 	"github.com/docker/distribution/manifest/schema2"
+	imagespecv1 "github.com/opencontainers/image-spec/specs-go/v1"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
-	dockerregistryserver "github.com/openshift/origin/pkg/dockerregistry/server"
 	imageapi "github.com/openshift/origin/pkg/image/api"
 	exutil "github.com/openshift/origin/test/extended/util"
 	testutil "github.com/openshift/origin/test/util"
@@ -28,6 +32,13 @@ const (
 	mirrorBlobTimeout = time.Second * 10
 	// this image has a high number of relatively small blobs
 	externalImageReference = "docker.io/openshift/origin-release:golang-1.4"
+	// this image is published as a schema2 manifest list with several per-platform children
+	multiArchImageReference = "docker.io/library/busybox:latest"
+	// this image is published as an OCI image index with several per-platform children
+	ociImageIndexReference = "docker.io/library/alpine:latest"
+	// set to "1" to push test images with BuildAndPushImageOfSizeWithLibrary instead of a local
+	// Docker daemon, for clusters where dockerd isn't available to the test host
+	useContainersImageEnvVar = "USE_CONTAINERS_IMAGE"
 )
 
 type cleanUpContainer struct {
@@ -38,15 +49,8 @@ type cleanUpContainer struct {
 var _ = g.Describe("[images] prune images", func() {
 	defer g.GinkgoRecover()
 	var oc = exutil.NewCLI("prune-images", exutil.KubeConfigPath())
-	var originalAcceptSchema2 *bool
 
 	g.JustBeforeEach(func() {
-		if originalAcceptSchema2 == nil {
-			accepts, err := doesRegistryAcceptSchema2(oc)
-			o.Expect(err).NotTo(o.HaveOccurred())
-			originalAcceptSchema2 = &accepts
-		}
-
 		err := exutil.WaitForBuilderAccount(oc.KubeClient().Core().ServiceAccounts(oc.Namespace()))
 		o.Expect(err).NotTo(o.HaveOccurred())
 
@@ -56,67 +60,71 @@ var _ = g.Describe("[images] prune images", func() {
 	})
 
 	g.Describe("of schema 1", func() {
-		g.JustBeforeEach(func() {
-			if *originalAcceptSchema2 {
-				g.By("ensure the registry does not accept schema 2")
-				err := ensureRegistryAcceptsSchema2(oc, false)
-				o.Expect(err).NotTo(o.HaveOccurred())
-			}
+		g.It("should prune old image", func() {
+			scope := exutil.WithRegistry(oc, exutil.RegistrySchema1)
+			testPruneImages(oc, scope, 1)
 		})
+	})
 
-		g.AfterEach(func() {
-			if *originalAcceptSchema2 {
-				err := ensureRegistryAcceptsSchema2(oc, true)
-				o.Expect(err).NotTo(o.HaveOccurred())
-			}
+	g.Describe("of schema 2", func() {
+		g.It("should prune old image with config", func() {
+			scope := exutil.WithRegistry(oc, exutil.RegistrySchema2)
+			testPruneImages(oc, scope, 2)
+		})
+	})
+
+	g.Describe("of OCI", func() {
+		g.It("should prune old OCI image with config", func() {
+			scope := exutil.WithRegistry(oc, exutil.RegistrySchema2)
+			testPruneOCIImages(oc, scope)
 		})
 
-		g.It("should prune old image", func() { testPruneImages(oc, 1) })
+		g.It("should prune old OCI image index and its unreferenced manifests", func() {
+			scope := exutil.WithRegistry(oc, exutil.RegistrySchema2)
+			testPruneOCIImageIndex(oc, scope)
+		})
 	})
 
-	g.Describe("of schema 2", func() {
-		g.JustBeforeEach(func() {
-			if !*originalAcceptSchema2 {
-				g.By("ensure the registry accepts schema 2")
-				err := ensureRegistryAcceptsSchema2(oc, true)
-				o.Expect(err).NotTo(o.HaveOccurred())
-			}
+	g.Describe("of manifest lists", func() {
+		g.It("should prune children no longer referenced by the manifest list", func() {
+			scope := exutil.WithRegistry(oc, exutil.RegistrySchema2)
+			testPruneManifestListImage(oc, scope)
 		})
+	})
 
-		g.AfterEach(func() {
-			if !*originalAcceptSchema2 {
-				err := ensureRegistryAcceptsSchema2(oc, false)
-				o.Expect(err).NotTo(o.HaveOccurred())
-			}
+	g.Describe("of signed images", func() {
+		g.It("should remove orphaned signatures of kept images with --prune-signatures=true", func() {
+			scope := exutil.WithRegistry(oc, exutil.RegistrySchema2)
+			testPruneSignedImages(oc, scope, "true")
+		})
+
+		g.It("should leave orphaned signatures of kept images alone with --prune-signatures=false", func() {
+			scope := exutil.WithRegistry(oc, exutil.RegistrySchema2)
+			testPruneSignedImages(oc, scope, "false")
 		})
 
-		g.It("should prune old image with config", func() { testPruneImages(oc, 2) })
+		g.It("should remove only orphaned signatures with --prune-signatures=orphans-only", func() {
+			scope := exutil.WithRegistry(oc, exutil.RegistrySchema2)
+			testPruneSignedImages(oc, scope, "orphans-only")
+		})
 	})
 
 	g.Describe("with default --all flag", func() {
-		g.AfterEach(func() {
-			if !*originalAcceptSchema2 {
-				err := ensureRegistryAcceptsSchema2(oc, false)
-				o.Expect(err).NotTo(o.HaveOccurred())
-			}
+		g.It("should prune both internally managed and external images", func() {
+			scope := exutil.WithRegistry(oc, exutil.RegistrySchema2)
+			testPruneAllImages(oc, scope, true, 2)
 		})
-
-		g.It("should prune both internally managed and external images", func() { testPruneAllImages(oc, true, 2) })
 	})
 
 	g.Describe("with --all=false flag", func() {
-		g.AfterEach(func() {
-			if !*originalAcceptSchema2 {
-				err := ensureRegistryAcceptsSchema2(oc, false)
-				o.Expect(err).NotTo(o.HaveOccurred())
-			}
+		g.It("should prune only internally managed images", func() {
+			scope := exutil.WithRegistry(oc, exutil.RegistrySchema2)
+			testPruneAllImages(oc, scope, false, 2)
 		})
-
-		g.It("should prune only internally managed images", func() { testPruneAllImages(oc, false, 2) })
 	})
 })
 
-func testPruneImages(oc *exutil.CLI, schemaVersion int) {
+func testPruneImages(oc *exutil.CLI, scope *exutil.RegistryScope, schemaVersion int) {
 	var mediaType string
 	switch schemaVersion {
 	case 1:
@@ -136,20 +144,17 @@ func testPruneImages(oc *exutil.CLI, schemaVersion int) {
 	cleanUp := cleanUpContainer{}
 	defer tearDownPruneImagesTest(oc, &cleanUp)
 
-	dClient, err := testutil.NewDockerClient()
-	o.Expect(err).NotTo(o.HaveOccurred())
-
-	g.By(fmt.Sprintf("build two images using Docker and push them as schema %d", schemaVersion))
-	imgPruneName, err := BuildAndPushImageOfSizeWithDocker(oc, dClient, isName, "latest", testImageSize, 2, outSink, true)
+	g.By(fmt.Sprintf("build two images and push them as schema %d", schemaVersion))
+	imgPruneName, err := buildAndPushTestImage(oc, scope, isName, "latest", testImageSize, 2, outSink, mediaType)
 	o.Expect(err).NotTo(o.HaveOccurred())
 	cleanUp.imageNames = append(cleanUp.imageNames, imgPruneName)
 	cleanUp.isNames = append(cleanUp.isNames, isName)
-	pruneSize, err := getRegistryStorageSize(oc)
+	pruneSize, err := getRegistryStorageSize(oc, scope)
 	o.Expect(err).NotTo(o.HaveOccurred())
-	imgKeepName, err := BuildAndPushImageOfSizeWithDocker(oc, dClient, isName, "latest", testImageSize, 2, outSink, true)
+	imgKeepName, err := buildAndPushTestImage(oc, scope, isName, "latest", testImageSize, 2, outSink, mediaType)
 	o.Expect(err).NotTo(o.HaveOccurred())
 	cleanUp.imageNames = append(cleanUp.imageNames, imgKeepName)
-	keepSize, err := getRegistryStorageSize(oc)
+	keepSize, err := getRegistryStorageSize(oc, scope)
 	o.Expect(err).NotTo(o.HaveOccurred())
 	o.Expect(pruneSize < keepSize).To(o.BeTrue())
 
@@ -185,7 +190,7 @@ func testPruneImages(oc *exutil.CLI, schemaVersion int) {
 		}
 	}
 
-	noConfirmSize, err := getRegistryStorageSize(oc)
+	noConfirmSize, err := getRegistryStorageSize(oc, scope)
 	o.Expect(err).NotTo(o.HaveOccurred())
 	o.Expect(noConfirmSize).To(o.Equal(keepSize))
 
@@ -203,7 +208,7 @@ func testPruneImages(oc *exutil.CLI, schemaVersion int) {
 		if !strings.Contains(output, layer.Name) {
 			o.Expect(output).To(o.ContainSubstring(layer.Name))
 		}
-		globally, inRepository, err := IsBlobStoredInRegistry(oc, digest.Digest(layer.Name), repoName)
+		globally, inRepository, err := IsBlobStoredInRegistry(oc, scope, digest.Digest(layer.Name), repoName)
 		o.Expect(err).NotTo(o.HaveOccurred())
 		o.Expect(globally).To(o.BeFalse())
 		o.Expect(inRepository).To(o.BeFalse())
@@ -215,13 +220,13 @@ func testPruneImages(oc *exutil.CLI, schemaVersion int) {
 		if !strings.Contains(output, layer.Name) {
 			o.Expect(output).NotTo(o.ContainSubstring(layer.Name))
 		}
-		globally, inRepository, err := IsBlobStoredInRegistry(oc, digest.Digest(layer.Name), repoName)
+		globally, inRepository, err := IsBlobStoredInRegistry(oc, scope, digest.Digest(layer.Name), repoName)
 		o.Expect(err).NotTo(o.HaveOccurred())
 		o.Expect(globally).To(o.BeTrue())
 		o.Expect(inRepository).To(o.BeTrue())
 	}
 
-	confirmSize, err := getRegistryStorageSize(oc)
+	confirmSize, err := getRegistryStorageSize(oc, scope)
 	o.Expect(err).NotTo(o.HaveOccurred())
 	g.By(fmt.Sprintf("confirming storage size: sizeOfKeepImage=%d <= sizeAfterPrune=%d < beforePruneSize=%d", imgKeep.DockerImageMetadata.Size, confirmSize, keepSize))
 	o.Expect(confirmSize >= imgKeep.DockerImageMetadata.Size).To(o.BeTrue())
@@ -230,9 +235,172 @@ func testPruneImages(oc *exutil.CLI, schemaVersion int) {
 	o.Expect(imgPrune.DockerImageMetadata.Size <= keepSize-confirmSize).To(o.BeTrue())
 }
 
-func testPruneAllImages(oc *exutil.CLI, setAllImagesToFalse bool, schemaVersion int) {
+func testPruneOCIImages(oc *exutil.CLI, scope *exutil.RegistryScope) {
 	isName := "prune"
-	repository := oc.Namespace() + "/" + isName
+	repoName := oc.Namespace() + "/" + isName
+
+	oc.SetOutputDir(exutil.TestContext.OutputDir)
+	outSink := g.GinkgoWriter
+
+	cleanUp := cleanUpContainer{}
+	defer tearDownPruneImagesTest(oc, &cleanUp)
+
+	g.By("build two OCI images using skopeo and push them")
+	imgPruneName, err := BuildAndPushImageOfSizeWithSkopeo(oc, scope, isName, "latest", testImageSize, 2, outSink)
+	o.Expect(err).NotTo(o.HaveOccurred())
+	cleanUp.imageNames = append(cleanUp.imageNames, imgPruneName)
+	cleanUp.isNames = append(cleanUp.isNames, isName)
+	pruneSize, err := getRegistryStorageSize(oc, scope)
+	o.Expect(err).NotTo(o.HaveOccurred())
+	imgKeepName, err := BuildAndPushImageOfSizeWithSkopeo(oc, scope, isName, "latest", testImageSize, 2, outSink)
+	o.Expect(err).NotTo(o.HaveOccurred())
+	cleanUp.imageNames = append(cleanUp.imageNames, imgKeepName)
+	keepSize, err := getRegistryStorageSize(oc, scope)
+	o.Expect(err).NotTo(o.HaveOccurred())
+	o.Expect(pruneSize < keepSize).To(o.BeTrue())
+
+	g.By("ensure uploaded image is an OCI image manifest")
+	imgPrune, err := oc.AsAdmin().Client().Images().Get(imgPruneName, metav1.GetOptions{})
+	o.Expect(err).NotTo(o.HaveOccurred())
+	o.Expect(imgPrune.DockerImageManifestMediaType).To(o.Equal(imagespecv1.MediaTypeImageManifest))
+	imgKeep, err := oc.AsAdmin().Client().Images().Get(imgKeepName, metav1.GetOptions{})
+	o.Expect(err).NotTo(o.HaveOccurred())
+	o.Expect(imgKeep.DockerImageManifestMediaType).To(o.Equal(imagespecv1.MediaTypeImageManifest))
+
+	g.By("prune the first OCI image uploaded (confirm)")
+	output, err := oc.WithoutNamespace().Run("adm").Args("prune", "images", "--keep-tag-revisions=1", "--keep-younger-than=0", "--confirm").Output()
+	o.Expect(err).NotTo(o.HaveOccurred())
+
+	g.By("verify images, layers and configs about to be pruned")
+	o.Expect(output).To(o.ContainSubstring(imgPruneName))
+	o.Expect(output).To(o.ContainSubstring(imgPrune.DockerImageMetadata.ID))
+	for _, layer := range imgPrune.DockerImageLayers {
+		o.Expect(output).To(o.ContainSubstring(layer.Name))
+		globally, inRepository, err := IsBlobStoredInRegistry(oc, scope, digest.Digest(layer.Name), repoName)
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(globally).To(o.BeFalse())
+		o.Expect(inRepository).To(o.BeFalse())
+	}
+	globally, inRepository, err := IsBlobStoredInRegistry(oc, scope, digest.Digest(imgPrune.DockerImageMetadata.ID), repoName)
+	o.Expect(err).NotTo(o.HaveOccurred())
+	o.Expect(globally).To(o.BeFalse())
+	o.Expect(inRepository).To(o.BeFalse())
+
+	o.Expect(output).NotTo(o.ContainSubstring(imgKeepName))
+	o.Expect(output).NotTo(o.ContainSubstring(imgKeep.DockerImageMetadata.ID))
+	for _, layer := range imgKeep.DockerImageLayers {
+		o.Expect(output).NotTo(o.ContainSubstring(layer.Name))
+		globally, inRepository, err := IsBlobStoredInRegistry(oc, scope, digest.Digest(layer.Name), repoName)
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(globally).To(o.BeTrue())
+		o.Expect(inRepository).To(o.BeTrue())
+	}
+}
+
+func testPruneManifestListImage(oc *exutil.CLI, scope *exutil.RegistryScope) {
+	isName := "prune-manifestlist"
+	repoName := oc.Namespace() + "/" + isName
+
+	oc.SetOutputDir(exutil.TestContext.OutputDir)
+
+	cleanUp := cleanUpContainer{}
+	defer tearDownPruneImagesTest(oc, &cleanUp)
+
+	outSink := g.GinkgoWriter
+	list, prunedChild, keptChild, err := pushManifestListIntoRegistry(oc, scope, multiArchImageReference, isName+":latest", outSink)
+	o.Expect(err).NotTo(o.HaveOccurred())
+	cleanUp.imageNames = append(cleanUp.imageNames, list.Name)
+	cleanUp.isNames = append(cleanUp.isNames, isName)
+
+	g.By("ensure the imported image is a schema2 manifest list")
+	o.Expect(list.DockerImageManifestMediaType).To(o.Equal(manifestlist.MediaTypeManifestList))
+	o.Expect(len(list.DockerImageManifestReferences)).To(o.BeNumerically(">=", 2))
+
+	g.By("untag the manifest list, leaving the kept child reachable only through its own tag")
+	err = oc.Run("tag").Args("-d", isName+":latest").Execute()
+	o.Expect(err).NotTo(o.HaveOccurred())
+
+	g.By("prune the manifest list's unreferenced child")
+	_, err = oc.WithoutNamespace().Run("adm").Args("prune", "images", "--keep-tag-revisions=0", "--keep-younger-than=0", "--confirm").Output()
+	o.Expect(err).NotTo(o.HaveOccurred())
+
+	for _, layer := range prunedChild.DockerImageLayers {
+		globally, inRepository, err := IsBlobStoredInRegistry(oc, scope, digest.Digest(layer.Name), repoName)
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(globally).To(o.BeFalse())
+		o.Expect(inRepository).To(o.BeFalse())
+	}
+
+	for _, layer := range keptChild.DockerImageLayers {
+		globally, inRepository, err := IsBlobStoredInRegistry(oc, scope, digest.Digest(layer.Name), repoName)
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(globally).To(o.BeTrue())
+		o.Expect(inRepository).To(o.BeTrue())
+	}
+
+	g.By("verify the manifest list itself was removed from the repository")
+	present, err := IsManifestInRegistry(oc, scope, digest.Digest(list.Name), repoName)
+	o.Expect(err).NotTo(o.HaveOccurred())
+	o.Expect(present).To(o.BeFalse())
+}
+
+// testPruneOCIImageIndex is the OCI-image-index analog of testPruneManifestListImage: it verifies that
+// pruning a node that fans out via application/vnd.oci.image.index.v1+json, rather than a Docker schema2
+// manifest list, still walks into its children correctly.
+func testPruneOCIImageIndex(oc *exutil.CLI, scope *exutil.RegistryScope) {
+	isName := "prune-ociindex"
+	repoName := oc.Namespace() + "/" + isName
+
+	oc.SetOutputDir(exutil.TestContext.OutputDir)
+
+	cleanUp := cleanUpContainer{}
+	defer tearDownPruneImagesTest(oc, &cleanUp)
+
+	outSink := g.GinkgoWriter
+	index, prunedChild, keptChild, err := pushManifestListIntoRegistry(oc, scope, ociImageIndexReference, isName+":latest", outSink)
+	o.Expect(err).NotTo(o.HaveOccurred())
+	cleanUp.imageNames = append(cleanUp.imageNames, index.Name)
+	cleanUp.isNames = append(cleanUp.isNames, isName)
+
+	g.By("ensure the imported image is an OCI image index")
+	o.Expect(index.DockerImageManifestMediaType).To(o.Equal(imagespecv1.MediaTypeImageIndex))
+	o.Expect(len(index.DockerImageManifestReferences)).To(o.BeNumerically(">=", 2))
+
+	g.By("untag the image index, leaving the kept child reachable only through its own tag")
+	err = oc.Run("tag").Args("-d", isName+":latest").Execute()
+	o.Expect(err).NotTo(o.HaveOccurred())
+
+	g.By("prune the image index's unreferenced child")
+	_, err = oc.WithoutNamespace().Run("adm").Args("prune", "images", "--keep-tag-revisions=0", "--keep-younger-than=0", "--confirm").Output()
+	o.Expect(err).NotTo(o.HaveOccurred())
+
+	for _, layer := range prunedChild.DockerImageLayers {
+		globally, inRepository, err := IsBlobStoredInRegistry(oc, scope, digest.Digest(layer.Name), repoName)
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(globally).To(o.BeFalse())
+		o.Expect(inRepository).To(o.BeFalse())
+	}
+
+	for _, layer := range keptChild.DockerImageLayers {
+		globally, inRepository, err := IsBlobStoredInRegistry(oc, scope, digest.Digest(layer.Name), repoName)
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(globally).To(o.BeTrue())
+		o.Expect(inRepository).To(o.BeTrue())
+	}
+
+	g.By("verify the image index itself was removed from the repository")
+	present, err := IsManifestInRegistry(oc, scope, digest.Digest(index.Name), repoName)
+	o.Expect(err).NotTo(o.HaveOccurred())
+	o.Expect(present).To(o.BeFalse())
+}
+
+// testPruneSignedImages exercises all three --prune-signatures modes ("true", "false", "orphans-only")
+// against the same scenario: one image that gets pruned outright (its signature disappears along with it
+// regardless of mode), and one kept image whose signature is revoked, leaving it orphaned (only "true" and
+// "orphans-only" are expected to clean that one up).
+func testPruneSignedImages(oc *exutil.CLI, scope *exutil.RegistryScope, pruneSignatures string) {
+	isName := "prune-signed"
+	repoName := oc.Namespace() + "/" + isName
 
 	oc.SetOutputDir(exutil.TestContext.OutputDir)
 	outSink := g.GinkgoWriter
@@ -243,8 +411,65 @@ func testPruneAllImages(oc *exutil.CLI, setAllImagesToFalse bool, schemaVersion
 	dClient, err := testutil.NewDockerClient()
 	o.Expect(err).NotTo(o.HaveOccurred())
 
-	g.By("build one image using Docker and push it")
-	managedImageName, err := BuildAndPushImageOfSizeWithDocker(oc, dClient, isName, "latest", testImageSize, 2, outSink, true)
+	g.By("build and push an image that will be pruned and sign it")
+	prunedImageName, err := BuildAndPushImageOfSizeWithDocker(oc, scope, dClient, isName, "latest", testImageSize, 2, outSink, true)
+	o.Expect(err).NotTo(o.HaveOccurred())
+	cleanUp.imageNames = append(cleanUp.imageNames, prunedImageName)
+	cleanUp.isNames = append(cleanUp.isNames, isName)
+	err = oc.Run("image").Args("sign", prunedImageName, fmt.Sprintf("%s/%s", repoName, "latest"), "--insecure").Execute()
+	o.Expect(err).NotTo(o.HaveOccurred())
+
+	g.By("build and push an image that will be kept, sign it and then revoke its signature")
+	keptImageName, err := BuildAndPushImageOfSizeWithDocker(oc, scope, dClient, isName, "latest", testImageSize, 2, outSink, true)
+	o.Expect(err).NotTo(o.HaveOccurred())
+	cleanUp.imageNames = append(cleanUp.imageNames, keptImageName)
+	err = oc.Run("image").Args("sign", keptImageName, fmt.Sprintf("%s/%s", repoName, "latest"), "--insecure").Execute()
+	o.Expect(err).NotTo(o.HaveOccurred())
+
+	keptImage, err := oc.AsAdmin().Client().Images().Get(keptImageName, metav1.GetOptions{})
+	o.Expect(err).NotTo(o.HaveOccurred())
+	o.Expect(len(keptImage.Signatures)).To(o.BeNumerically(">", 0))
+
+	g.By("revoke the kept image's signature, leaving it orphaned")
+	err = oc.AsAdmin().WithoutNamespace().Run("adm").Args("verify-image-signature", keptImageName, "--remove-all").Execute()
+	o.Expect(err).NotTo(o.HaveOccurred())
+
+	g.By(fmt.Sprintf("prune images with --prune-signatures=%s", pruneSignatures))
+	_, err = oc.WithoutNamespace().Run("adm").Args("prune", "images", "--keep-tag-revisions=1", "--keep-younger-than=0", fmt.Sprintf("--prune-signatures=%s", pruneSignatures), "--confirm").Output()
+	o.Expect(err).NotTo(o.HaveOccurred())
+
+	g.By("verify the pruned image's signatures are gone from the registry regardless of mode")
+	prunedHasSignature, err := IsSignatureStoredInRegistry(oc, scope, digest.Digest(prunedImageName), repoName)
+	o.Expect(err).NotTo(o.HaveOccurred())
+	o.Expect(prunedHasSignature).To(o.BeFalse())
+
+	_, err = oc.AsAdmin().Client().Images().Get(keptImageName, metav1.GetOptions{})
+	o.Expect(err).NotTo(o.HaveOccurred())
+	keptHasSignature, err := IsSignatureStoredInRegistry(oc, scope, digest.Digest(keptImageName), repoName)
+	o.Expect(err).NotTo(o.HaveOccurred())
+
+	switch pruneSignatures {
+	case "false":
+		g.By("verify the kept image's orphaned signature was left alone")
+		o.Expect(keptHasSignature).To(o.BeTrue())
+	default:
+		g.By("verify the kept image's orphaned signature was removed")
+		o.Expect(keptHasSignature).To(o.BeFalse())
+	}
+}
+
+func testPruneAllImages(oc *exutil.CLI, scope *exutil.RegistryScope, setAllImagesToFalse bool, schemaVersion int) {
+	isName := "prune"
+	repository := oc.Namespace() + "/" + isName
+
+	oc.SetOutputDir(exutil.TestContext.OutputDir)
+	outSink := g.GinkgoWriter
+
+	cleanUp := cleanUpContainer{}
+	defer tearDownPruneImagesTest(oc, &cleanUp)
+
+	g.By("build one image and push it")
+	managedImageName, err := buildAndPushTestImage(oc, scope, isName, "latest", testImageSize, 2, outSink, schema2.MediaTypeManifest)
 	o.Expect(err).NotTo(o.HaveOccurred())
 	cleanUp.imageNames = append(cleanUp.imageNames, managedImageName)
 	cleanUp.isNames = append(cleanUp.isNames, isName)
@@ -253,7 +478,7 @@ func testPruneAllImages(oc *exutil.CLI, setAllImagesToFalse bool, schemaVersion
 	managedImage, err := oc.AsAdmin().Client().Images().Get(managedImageName, metav1.GetOptions{})
 	o.Expect(err).NotTo(o.HaveOccurred())
 
-	externalImage, blobdgst, err := importImageAndMirrorItsSmallestBlob(oc, externalImageReference, "origin-release:latest")
+	externalImage, blobdgst, err := importImageAndMirrorItsSmallestBlob(oc, scope, externalImageReference, "origin-release:latest")
 	o.Expect(err).NotTo(o.HaveOccurred())
 	cleanUp.imageNames = append(cleanUp.imageNames, externalImage.Name)
 	cleanUp.isNames = append(cleanUp.isNames, "origin-release")
@@ -266,7 +491,7 @@ func testPruneAllImages(oc *exutil.CLI, setAllImagesToFalse bool, schemaVersion
 
 		for _, layer := range managedImage.DockerImageLayers {
 			o.Expect(output).To(o.ContainSubstring(layer.Name))
-			globally, inRepository, err := IsBlobStoredInRegistry(oc, digest.Digest(layer.Name), repository)
+			globally, inRepository, err := IsBlobStoredInRegistry(oc, scope, digest.Digest(layer.Name), repository)
 			o.Expect(err).NotTo(o.HaveOccurred())
 			o.Expect(globally).To(o.Equal(dryRun))
 			o.Expect(inRepository).To(o.Equal(dryRun))
@@ -288,7 +513,7 @@ func testPruneAllImages(oc *exutil.CLI, setAllImagesToFalse bool, schemaVersion
 			if blobdgst.String() != layer.Name {
 				continue
 			}
-			globally, inRepository, err := IsBlobStoredInRegistry(oc, digest.Digest(layer.Name), repository)
+			globally, inRepository, err := IsBlobStoredInRegistry(oc, scope, digest.Digest(layer.Name), repository)
 			o.Expect(err).NotTo(o.HaveOccurred())
 			o.Expect(globally).To(o.Equal(dryRun || setAllImagesToFalse))
 			// mirrored blobs are not linked into any repository/_layers directory
@@ -315,6 +540,21 @@ func testPruneAllImages(oc *exutil.CLI, setAllImagesToFalse bool, schemaVersion
 	checkAdminPruneOutput(output, false)
 }
 
+// buildAndPushTestImage pushes a synthetic test image of the given mediaType, using a local Docker daemon
+// by default or, when USE_CONTAINERS_IMAGE=1 is set, BuildAndPushImageOfSizeWithLibrary so the prune suite
+// can run on hosts without dockerd.
+func buildAndPushTestImage(oc *exutil.CLI, scope *exutil.RegistryScope, isName, tag string, size, layers int, outSink io.Writer, mediaType string) (string, error) {
+	if os.Getenv(useContainersImageEnvVar) == "1" {
+		return BuildAndPushImageOfSizeWithLibrary(oc, scope, isName, tag, size, layers, mediaType, outSink)
+	}
+
+	dClient, err := testutil.NewDockerClient()
+	if err != nil {
+		return "", err
+	}
+	return BuildAndPushImageOfSizeWithDocker(oc, scope, dClient, isName, tag, size, layers, outSink, true)
+}
+
 func tearDownPruneImagesTest(oc *exutil.CLI, cleanUp *cleanUpContainer) {
 	for _, image := range cleanUp.imageNames {
 		err := oc.AsAdmin().Client().Images().Delete(image)
@@ -330,10 +570,10 @@ func tearDownPruneImagesTest(oc *exutil.CLI, cleanUp *cleanUpContainer) {
 	}
 }
 
-func getRegistryStorageSize(oc *exutil.CLI) (int64, error) {
+func getRegistryStorageSize(oc *exutil.CLI, scope *exutil.RegistryScope) (int64, error) {
 	ns := oc.Namespace()
 	defer oc.SetNamespace(ns)
-	out, err := oc.SetNamespace(metav1.NamespaceDefault).AsAdmin().Run("rsh").Args("dc/docker-registry", "du", "--bytes", "--summarize", "/registry/docker/registry").Output()
+	out, err := oc.SetNamespace(metav1.NamespaceDefault).AsAdmin().Run("rsh").Args(scope.DeploymentConfigRef(), "du", "--bytes", "--summarize", "/registry/docker/registry").Output()
 	if err != nil {
 		return 0, err
 	}
@@ -350,53 +590,6 @@ func getRegistryStorageSize(oc *exutil.CLI) (int64, error) {
 	return size, nil
 }
 
-func doesRegistryAcceptSchema2(oc *exutil.CLI) (bool, error) {
-	ns := oc.Namespace()
-	defer oc.SetNamespace(ns)
-	env, err := oc.SetNamespace(metav1.NamespaceDefault).AsAdmin().Run("env").Args("dc/docker-registry", "--list").Output()
-	if err != nil {
-		return false, err
-	}
-
-	return strings.Contains(env, fmt.Sprintf("%s=true", dockerregistryserver.AcceptSchema2EnvVar)), nil
-}
-
-// ensureRegistryAcceptsSchema2 checks whether the registry is configured to accept manifests V2 schema 2 or
-// not. If the result doesn't match given accept argument, registry's deployment config is updated accordingly
-// and the function blocks until the registry is re-deployed and ready for new requests.
-func ensureRegistryAcceptsSchema2(oc *exutil.CLI, accept bool) error {
-	ns := oc.Namespace()
-	oc = oc.SetNamespace(metav1.NamespaceDefault).AsAdmin()
-	defer oc.SetNamespace(ns)
-	env, err := oc.Run("env").Args("dc/docker-registry", "--list").Output()
-	if err != nil {
-		return err
-	}
-
-	value := fmt.Sprintf("%s=%t", dockerregistryserver.AcceptSchema2EnvVar, accept)
-	if strings.Contains(env, value) {
-		if accept {
-			g.By("docker-registry is already configured to accept schema 2")
-		} else {
-			g.By("docker-registry is already configured to refuse schema 2")
-		}
-		return nil
-	}
-
-	dc, err := oc.Client().DeploymentConfigs(metav1.NamespaceDefault).Get("docker-registry", metav1.GetOptions{})
-	if err != nil {
-		return err
-	}
-	waitForVersion := dc.Status.LatestVersion + 1
-
-	g.By("configuring Docker registry to accept schema 2")
-	err = oc.Run("env").Args("dc/docker-registry", value).Execute()
-	if err != nil {
-		return fmt.Errorf("failed to update registry's environment with %s: %v", &waitForVersion, err)
-	}
-	return exutil.WaitForRegistry(oc.AdminClient(), oc.AdminKubeClient(), &waitForVersion, oc)
-}
-
 type byLayerSize []imageapi.ImageLayer
 
 func (bls byLayerSize) Len() int      { return len(bls) }
@@ -411,7 +604,7 @@ func (bls byLayerSize) Less(i, j int) bool {
 	return false
 }
 
-func importImageAndMirrorItsSmallestBlob(oc *exutil.CLI, imageReference, destISTag string) (*imageapi.Image, digest.Digest, error) {
+func importImageAndMirrorItsSmallestBlob(oc *exutil.CLI, scope *exutil.RegistryScope, imageReference, destISTag string) (*imageapi.Image, digest.Digest, error) {
 	g.By(fmt.Sprintf("importing external image %q", imageReference))
 	err := oc.Run("tag").Args("--source=docker", imageReference, destISTag).Execute()
 	if err != nil {
@@ -446,10 +639,69 @@ func importImageAndMirrorItsSmallestBlob(oc *exutil.CLI, imageReference, destIST
 
 	layer := tmpLayers[0]
 	g.By(fmt.Sprintf("mirroring image's blob of size=%d in repository %q", layer.LayerSize, isName))
-	err = MirrorBlobInRegistry(oc, digest.Digest(layer.Name), oc.Namespace()+"/"+isName, mirrorBlobTimeout)
+	err = MirrorBlobInRegistry(oc, scope, digest.Digest(layer.Name), oc.Namespace()+"/"+isName, mirrorBlobTimeout)
 	if err != nil {
 		return nil, "", err
 	}
 
 	return &istag.Image, digest.Digest(tmpLayers[0].Name), nil
 }
+
+// pushManifestListIntoRegistry pushes imageReference - manifest list (or OCI image index), every child
+// manifest and every blob they reference - into the internal registry instance backing scope via
+// `skopeo copy --all`, rather than importing it by metadata alone with `oc tag --source=docker`: a metadata
+// import never stores a single byte in the internal registry, which would make any later check of the
+// registry's own blob/manifest store pass vacuously regardless of what pruning actually did. It additionally
+// tags the kept child directly, by digest, so that child stays reachable once the list itself is untagged.
+func pushManifestListIntoRegistry(oc *exutil.CLI, scope *exutil.RegistryScope, imageReference, destISTag string, outSink io.Writer) (list, prunedChild, keptChild *imageapi.Image, err error) {
+	isName, tag, ok := imageapi.SplitImageStreamTag(destISTag)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("failed to parse image stream tag %q", destISTag)
+	}
+
+	g.By(fmt.Sprintf("pushing external manifest list %q into the internal registry", imageReference))
+	pushSpec := scope.PushSpec(oc.Namespace(), isName, tag)
+	cmd := exec.Command("skopeo", "copy", "--all", "--dest-tls-verify=false", "docker://"+imageReference, "docker://"+pushSpec)
+	cmd.Stdout = outSink
+	cmd.Stderr = outSink
+	if err := cmd.Run(); err != nil {
+		return nil, nil, nil, fmt.Errorf("skopeo copy --all failed: %v", err)
+	}
+
+	err = exutil.WaitForAnImageStreamTag(oc, oc.Namespace(), isName, tag)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	istag, err := oc.Client().ImageStreamTags(oc.Namespace()).Get(isName, tag)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(istag.Image.DockerImageManifestReferences) < 2 {
+		return nil, nil, nil, fmt.Errorf("expected %q to resolve to a manifest list with at least two children, got %d", imageReference, len(istag.Image.DockerImageManifestReferences))
+	}
+
+	prunedRef := istag.Image.DockerImageManifestReferences[0]
+	keptRef := istag.Image.DockerImageManifestReferences[1]
+
+	pruned, err := oc.AsAdmin().Client().Images().Get(prunedRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	kept, err := oc.AsAdmin().Client().Images().Get(keptRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	keptISTag := isName + ":kept"
+	g.By(fmt.Sprintf("tagging the kept child %s directly so it outlives the manifest list", keptRef.Name))
+	err = oc.Run("tag").Args(fmt.Sprintf("%s@%s", isName, keptRef.Name), keptISTag).Execute()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	pIsName, pTag, _ := imageapi.SplitImageStreamTag(keptISTag)
+	if err := exutil.WaitForAnImageStreamTag(oc, oc.Namespace(), pIsName, pTag); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return &istag.Image, pruned, kept, nil
+}
@@ -0,0 +1,328 @@
+package images
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/containers/image/v5/copy"
+	"github.com/containers/image/v5/signature"
+	"github.com/containers/image/v5/transports/alltransports"
+	"github.com/containers/image/v5/types"
+	"github.com/docker/distribution/digest"
+	imagespecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	exutil "github.com/openshift/origin/test/extended/util"
+)
+
+// IsManifestInRegistry reports whether the manifest identified by dgst is still present in repoName's
+// manifest revision store on the registry instance backing scope. It mirrors how the registry lays out
+// manifest links on disk: presence of
+// /registry/docker/registry/v2/repositories/<repo>/_manifests/revisions/<algorithm>/<hex>/link is what makes
+// a manifest pullable by digest, and is what a confirmed prune run is expected to remove.
+func IsManifestInRegistry(oc *exutil.CLI, scope *exutil.RegistryScope, dgst digest.Digest, repoName string) (bool, error) {
+	ns := oc.Namespace()
+	defer oc.SetNamespace(ns)
+
+	linkPath := fmt.Sprintf("/registry/docker/registry/v2/repositories/%s/_manifests/revisions/%s/%s/link", repoName, dgst.Algorithm(), dgst.Hex())
+	output, err := oc.SetNamespace(metav1.NamespaceDefault).AsAdmin().Run("rsh").Args(scope.DeploymentConfigRef(), "ls", linkPath).Output()
+	if err == nil {
+		return true, nil
+	}
+	// `ls` on a missing link fails with this message; any other failure (pod unreachable, bad rsh target,
+	// etc.) is a genuine error the caller needs to see, not a "manifest absent" result.
+	if strings.Contains(output, "No such file or directory") || strings.Contains(err.Error(), "No such file or directory") {
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to check for manifest link %s: %v (output: %s)", linkPath, err, output)
+}
+
+// IsSignatureStoredInRegistry reports whether any detached signature remains on disk for the image
+// identified by imageDigest in repoName's signature store on the registry instance backing scope.
+func IsSignatureStoredInRegistry(oc *exutil.CLI, scope *exutil.RegistryScope, imageDigest digest.Digest, repoName string) (bool, error) {
+	ns := oc.Namespace()
+	defer oc.SetNamespace(ns)
+
+	sigDir := fmt.Sprintf("/registry/docker/registry/v2/repositories/%s/_manifests/revisions/%s/%s/signatures", repoName, imageDigest.Algorithm(), imageDigest.Hex())
+	output, err := oc.SetNamespace(metav1.NamespaceDefault).AsAdmin().Run("rsh").Args(scope.DeploymentConfigRef(), "find", sigDir, "-mindepth", "2", "-name", "link").Output()
+	if err != nil {
+		if strings.Contains(output, "No such file or directory") || strings.Contains(err.Error(), "No such file or directory") {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check for signatures of %s: %v (output: %s)", imageDigest, err, output)
+	}
+	return strings.TrimSpace(output) != "", nil
+}
+
+// BuildAndPushImageOfSizeWithSkopeo builds an OCI image layout containing the requested number of
+// randomly-filled layers of roughly the given size (in bytes) and pushes it into the image stream isName:tag
+// using skopeo, so the OCI prune specs don't depend on a local Docker daemon supporting OCI media types.
+func BuildAndPushImageOfSizeWithSkopeo(oc *exutil.CLI, scope *exutil.RegistryScope, isName, tag string, size, layers int, outSink io.Writer) (string, error) {
+	layoutDir, err := ioutil.TempDir("", "prune-oci-layout")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(layoutDir)
+
+	if err := writeOCIImageLayout(layoutDir, size, layers); err != nil {
+		return "", err
+	}
+
+	pushSpec := scope.PushSpec(oc.Namespace(), isName, tag)
+	cmd := exec.Command("skopeo", "copy", "--dest-tls-verify=false", "oci:"+layoutDir, "docker://"+pushSpec)
+	cmd.Stdout = outSink
+	cmd.Stderr = outSink
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("skopeo copy failed: %v", err)
+	}
+
+	if err := exutil.WaitForAnImageStreamTag(oc, oc.Namespace(), isName, tag); err != nil {
+		return "", err
+	}
+	istag, err := oc.Client().ImageStreamTags(oc.Namespace()).Get(isName, tag)
+	if err != nil {
+		return "", err
+	}
+	return istag.Image.Name, nil
+}
+
+// BuildAndPushImageOfSizeWithLibrary builds the same kind of synthetic OCI image layout as
+// BuildAndPushImageOfSizeWithSkopeo, then pushes it to isName:tag on the registry instance backing scope
+// using github.com/containers/image/v5's copy.Image directly instead of shelling out to the skopeo binary,
+// so the prune suite can also run on hosts with neither a Docker daemon nor a skopeo install. mediaType
+// selects the manifest format the push ends up with; anything other than the OCI image manifest format the
+// layout is already written in is requested via copy.Options.ForceManifestMIMEType. Forcing schema1 is
+// best-effort: c/image's docker destination only really knows how to force schema2 or OCI, so callers that
+// need a guaranteed schema1 manifest should use BuildAndPushImageOfSizeWithDocker against the schema1-only
+// registry instance instead.
+func BuildAndPushImageOfSizeWithLibrary(oc *exutil.CLI, scope *exutil.RegistryScope, isName, tag string, size, layers int, mediaType string, outSink io.Writer) (string, error) {
+	layoutDir, err := ioutil.TempDir("", "prune-library-layout")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(layoutDir)
+
+	if err := writeOCIImageLayout(layoutDir, size, layers); err != nil {
+		return "", err
+	}
+
+	srcRef, err := alltransports.ParseImageName("oci:" + layoutDir)
+	if err != nil {
+		return "", err
+	}
+	destRef, err := alltransports.ParseImageName("docker://" + scope.PushSpec(oc.Namespace(), isName, tag))
+	if err != nil {
+		return "", err
+	}
+
+	policyContext, err := signature.NewPolicyContext(&signature.Policy{Default: signature.PolicyRequirements{signature.NewPRInsecureAcceptAnything()}})
+	if err != nil {
+		return "", err
+	}
+	defer policyContext.Destroy()
+
+	copyOpts := &copy.Options{
+		DestinationCtx: &types.SystemContext{DockerInsecureSkipTLSVerify: types.OptionalBoolTrue},
+		ReportWriter:   outSink,
+	}
+	if mediaType != imagespecv1.MediaTypeImageManifest {
+		copyOpts.ForceManifestMIMEType = mediaType
+	}
+	if _, err := copy.Image(context.Background(), policyContext, destRef, srcRef, copyOpts); err != nil {
+		return "", fmt.Errorf("containers/image copy failed: %v", err)
+	}
+
+	if err := exutil.WaitForAnImageStreamTag(oc, oc.Namespace(), isName, tag); err != nil {
+		return "", err
+	}
+	istag, err := oc.Client().ImageStreamTags(oc.Namespace()).Get(isName, tag)
+	if err != nil {
+		return "", err
+	}
+	return istag.Image.Name, nil
+}
+
+// ociDescriptor, ociManifest and ociIndex mirror the JSON shape of the corresponding OCI image-spec types
+// closely enough to round-trip through skopeo and the registry.
+type ociDescriptor struct {
+	MediaType string        `json:"mediaType"`
+	Digest    digest.Digest `json:"digest"`
+	Size      int64         `json:"size"`
+}
+
+type ociManifest struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	MediaType     string          `json:"mediaType"`
+	Config        ociDescriptor   `json:"config"`
+	Layers        []ociDescriptor `json:"layers"`
+}
+
+type ociIndex struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	MediaType     string          `json:"mediaType"`
+	Manifests     []ociDescriptor `json:"manifests"`
+}
+
+// writeOCIImageLayout creates a minimal valid OCI image layout (oci-layout, index.json, blobs/sha256/...)
+// under dir, consisting of `layers` randomly-filled gzip layers of approximately `size` bytes each, a
+// config blob and an OCI image manifest referencing them.
+func writeOCIImageLayout(dir string, size, layers int) error {
+	blobsDir := filepath.Join(dir, "blobs", "sha256")
+	if err := os.MkdirAll(blobsDir, 0755); err != nil {
+		return err
+	}
+
+	layerDescriptors := make([]ociDescriptor, 0, layers)
+	diffIDs := make([]string, 0, layers)
+	for i := 0; i < layers; i++ {
+		dgst, layerSize, diffID, err := writeRandomLayerBlob(blobsDir, size)
+		if err != nil {
+			return err
+		}
+		layerDescriptors = append(layerDescriptors, ociDescriptor{
+			MediaType: "application/vnd.oci.image.layer.v1.tar+gzip",
+			Digest:    dgst,
+			Size:      layerSize,
+		})
+		diffIDs = append(diffIDs, diffID.String())
+	}
+
+	configDigest, configSize, err := writeJSONBlob(blobsDir, map[string]interface{}{
+		"architecture": "amd64",
+		"os":           "linux",
+		"rootfs":       map[string]interface{}{"type": "layers", "diff_ids": diffIDs},
+	})
+	if err != nil {
+		return err
+	}
+
+	manifest := ociManifest{
+		SchemaVersion: 2,
+		MediaType:     imagespecv1.MediaTypeImageManifest,
+		Config:        ociDescriptor{MediaType: "application/vnd.oci.image.config.v1+json", Digest: configDigest, Size: configSize},
+		Layers:        layerDescriptors,
+	}
+
+	manifestDigest, manifestSize, err := writeJSONBlob(blobsDir, manifest)
+	if err != nil {
+		return err
+	}
+
+	index := ociIndex{
+		SchemaVersion: 2,
+		MediaType:     imagespecv1.MediaTypeImageIndex,
+		Manifests: []ociDescriptor{
+			{MediaType: imagespecv1.MediaTypeImageManifest, Digest: manifestDigest, Size: manifestSize},
+		},
+	}
+
+	indexBytes, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "index.json"), indexBytes, 0644); err != nil {
+		return err
+	}
+
+	layout := struct {
+		ImageLayoutVersion string `json:"imageLayoutVersion"`
+	}{ImageLayoutVersion: "1.0.0"}
+	layoutBytes, err := json.Marshal(layout)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, "oci-layout"), layoutBytes, 0644)
+}
+
+// writeRandomLayerBlob writes a randomly-filled, gzip-compressed tar layer into blobsDir and returns the
+// digest and size of the compressed blob (what the manifest's layer descriptor references) alongside diffID,
+// the digest of its uncompressed tar content (what the image config's rootfs.diff_ids must list, in the same
+// order as the manifest's layers, for the config to be internally consistent).
+func writeRandomLayerBlob(blobsDir string, size int) (blobDigest digest.Digest, blobSize int64, diffID digest.Digest, err error) {
+	content := make([]byte, size)
+	if _, err := rand.Read(content); err != nil {
+		return "", 0, "", err
+	}
+
+	var tarContent bytes.Buffer
+	tw := tar.NewWriter(&tarContent)
+	if err := tw.WriteHeader(&tar.Header{Name: "data", Mode: 0644, Size: int64(len(content))}); err != nil {
+		return "", 0, "", err
+	}
+	if _, err := tw.Write(content); err != nil {
+		return "", 0, "", err
+	}
+	if err := tw.Close(); err != nil {
+		return "", 0, "", err
+	}
+	diffID = digest.FromBytes(tarContent.Bytes())
+
+	f, err := ioutil.TempFile(blobsDir, "layer")
+	if err != nil {
+		return "", 0, "", err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	gzw := gzip.NewWriter(f)
+	if _, err := gzw.Write(tarContent.Bytes()); err != nil {
+		return "", 0, "", err
+	}
+	if err := gzw.Close(); err != nil {
+		return "", 0, "", err
+	}
+
+	blobDigest, blobSize, err = digestAndMoveBlob(f.Name(), blobsDir)
+	return blobDigest, blobSize, diffID, err
+}
+
+func writeJSONBlob(blobsDir string, v interface{}) (digest.Digest, int64, error) {
+	f, err := ioutil.TempFile(blobsDir, "blob")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	if err := enc.Encode(v); err != nil {
+		return "", 0, err
+	}
+
+	return digestAndMoveBlob(f.Name(), blobsDir)
+}
+
+// digestAndMoveBlob computes the sha256 digest of the file at path and renames it into blobsDir under that
+// digest, as required by the OCI image layout spec.
+func digestAndMoveBlob(path, blobsDir string) (digest.Digest, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	dgst, err := digest.FromReader(f)
+	f.Close()
+	if err != nil {
+		return "", 0, err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", 0, err
+	}
+	dest := filepath.Join(blobsDir, dgst.Hex())
+	if err := os.Rename(path, dest); err != nil {
+		return "", 0, err
+	}
+	return dgst, fi.Size(), nil
+}
@@ -0,0 +1,208 @@
+package util
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	dockerregistryserver "github.com/openshift/origin/pkg/dockerregistry/server"
+)
+
+// RegistrySchema identifies which manifest schema an internal registry instance is configured to accept.
+type RegistrySchema string
+
+const (
+	// RegistrySchema1 selects the registry instance that only accepts schema1 manifests.
+	RegistrySchema1 RegistrySchema = "schema1"
+	// RegistrySchema2 selects the registry instance that accepts schema2 and OCI manifests.
+	RegistrySchema2 RegistrySchema = "schema2"
+
+	schema1DeploymentConfigName = "docker-registry-schema1"
+	schema2DeploymentConfigName = "docker-registry"
+)
+
+// RegistryScope pins a CLI to one of the two internal registry instances so schema1 and schema2 (and OCI)
+// specs can run concurrently against independent DeploymentConfigs instead of flipping a shared registry's
+// accept-schema2 environment variable between runs.
+type RegistryScope struct {
+	oc     *CLI
+	dcName string
+}
+
+// WithRegistry returns a scope bound to the docker-registry instance that accepts the given schema,
+// provisioning the schema1-only instance on first use if it does not yet exist.
+func WithRegistry(oc *CLI, schema RegistrySchema) *RegistryScope {
+	switch schema {
+	case RegistrySchema1:
+		if err := ensureSchema1Registry(oc); err != nil {
+			FatalErr(err)
+		}
+		return &RegistryScope{oc: oc, dcName: schema1DeploymentConfigName}
+	case RegistrySchema2:
+		if err := ensureRegistryAcceptsOCI(oc); err != nil {
+			FatalErr(err)
+		}
+		return &RegistryScope{oc: oc, dcName: schema2DeploymentConfigName}
+	default:
+		FatalErr(fmt.Errorf("unknown registry schema %q", schema))
+		return nil
+	}
+}
+
+// DeploymentConfigRef returns the oc-style reference (e.g. "dc/docker-registry") of the DeploymentConfig
+// backing this scope's registry instance.
+func (s *RegistryScope) DeploymentConfigRef() string {
+	return "dc/" + s.dcName
+}
+
+// ServiceName returns the name of the Service fronting this scope's registry instance.
+func (s *RegistryScope) ServiceName() string {
+	return s.dcName
+}
+
+// PushSpec returns the in-cluster pull spec (service/namespace/name:tag) that a push destined for this
+// scope's registry instance should target.
+func (s *RegistryScope) PushSpec(namespace, isName, tag string) string {
+	return fmt.Sprintf("%s.%s.svc:5000/%s/%s:%s", s.ServiceName(), metav1.NamespaceDefault, namespace, isName, tag)
+}
+
+// ensureSchema1Registry stands up a second docker-registry DeploymentConfig, cloned from the default one,
+// with schema2 and OCI acceptance turned off, along with a matching Service. It is a no-op if the instance
+// already exists.
+func ensureSchema1Registry(oc *CLI) error {
+	ns := oc.Namespace()
+	admin := oc.SetNamespace(metav1.NamespaceDefault).AsAdmin()
+	defer oc.SetNamespace(ns)
+
+	if _, err := admin.Client().DeploymentConfigs(metav1.NamespaceDefault).Get(schema1DeploymentConfigName, metav1.GetOptions{}); err == nil {
+		return nil
+	}
+
+	base, err := admin.Client().DeploymentConfigs(metav1.NamespaceDefault).Get(schema2DeploymentConfigName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	clone := base.DeepCopy()
+	clone.ObjectMeta = metav1.ObjectMeta{Name: schema1DeploymentConfigName, Namespace: metav1.NamespaceDefault}
+	// Reset the copied status: a stale, nonzero LatestVersion carried over from the already-rolled-out
+	// schema2 DC would make the post-create wait below target the wrong rollout.
+	clone.Status.LatestVersion = 0
+	clone.Spec.Selector = map[string]string{"deploymentconfig": schema1DeploymentConfigName}
+	if clone.Spec.Template != nil {
+		clone.Spec.Template.Labels = map[string]string{"deploymentconfig": schema1DeploymentConfigName}
+		// Give the clone its own storage - otherwise it would mount the same backing volume as the
+		// schema2 instance and the two wouldn't be the independent registries this scope is meant to
+		// provide. ConfigMap/Secret volumes (serving certs, registry config) are left alone since those
+		// should stay identical between the two instances.
+		isolateStorageVolumes(&clone.Spec.Template.Spec)
+		// Bake the accept-schema2/accept-OCI env vars into the pod template before Create rather than
+		// setting them afterwards with `oc env`, so the clone only ever rolls out once - flipping env vars
+		// post-create would trigger a second rollout that races whatever the caller waits for below.
+		setContainerEnv(clone.Spec.Template.Spec.Containers, dockerregistryserver.AcceptSchema2EnvVar, "false")
+		setContainerEnv(clone.Spec.Template.Spec.Containers, dockerregistryserver.AcceptOCIEnvVar, "false")
+	}
+	created, err := admin.Client().DeploymentConfigs(metav1.NamespaceDefault).Create(clone)
+	if err != nil {
+		return err
+	}
+
+	baseSvc, err := admin.KubeClient().Core().Services(metav1.NamespaceDefault).Get(schema2DeploymentConfigName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	svcClone := baseSvc.DeepCopy()
+	svcClone.ObjectMeta = metav1.ObjectMeta{Name: schema1DeploymentConfigName, Namespace: metav1.NamespaceDefault}
+	svcClone.Spec.ClusterIP = ""
+	svcClone.Spec.Selector = map[string]string{"deploymentconfig": schema1DeploymentConfigName}
+	if _, err := admin.KubeClient().Core().Services(metav1.NamespaceDefault).Create(svcClone); err != nil {
+		return err
+	}
+
+	waitForVersion := created.Status.LatestVersion + 1
+	return WaitForRegistry(admin.AdminClient(), admin.AdminKubeClient(), &waitForVersion, admin)
+}
+
+// ensureRegistryAcceptsOCI makes sure the default docker-registry DeploymentConfig has both schema2 and OCI
+// manifest acceptance turned on - schema2 acceptance isn't guaranteed by the cluster default any more than
+// OCI acceptance is, and every schema2 (and OCI) spec needs it. It is a no-op if both are already set.
+func ensureRegistryAcceptsOCI(oc *CLI) error {
+	ns := oc.Namespace()
+	admin := oc.SetNamespace(metav1.NamespaceDefault).AsAdmin()
+	defer oc.SetNamespace(ns)
+
+	dc, err := admin.Client().DeploymentConfigs(metav1.NamespaceDefault).Get(schema2DeploymentConfigName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if dc.Spec.Template == nil {
+		return fmt.Errorf("deploymentconfig %q has no pod template", schema2DeploymentConfigName)
+	}
+	containers := dc.Spec.Template.Spec.Containers
+	if containerEnvSet(containers, dockerregistryserver.AcceptSchema2EnvVar, "true") &&
+		containerEnvSet(containers, dockerregistryserver.AcceptOCIEnvVar, "true") {
+		return nil
+	}
+
+	if err := admin.Run("env").Args(
+		"dc/"+schema2DeploymentConfigName,
+		fmt.Sprintf("%s=true", dockerregistryserver.AcceptSchema2EnvVar),
+		fmt.Sprintf("%s=true", dockerregistryserver.AcceptOCIEnvVar),
+	).Execute(); err != nil {
+		return err
+	}
+
+	waitForVersion := dc.Status.LatestVersion + 1
+	return WaitForRegistry(admin.AdminClient(), admin.AdminKubeClient(), &waitForVersion, admin)
+}
+
+// isolateStorageVolumes replaces any non-ConfigMap/Secret volume in spec with an EmptyDir of its own, so a
+// cloned DeploymentConfig doesn't end up sharing the original's backing storage volume.
+func isolateStorageVolumes(spec *corev1.PodSpec) {
+	for i, v := range spec.Volumes {
+		if v.ConfigMap != nil || v.Secret != nil {
+			continue
+		}
+		spec.Volumes[i].VolumeSource = corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}
+	}
+}
+
+// containerEnvSet reports whether every container in containers already has an env var named name set to
+// value. It mirrors the "every container" semantics setContainerEnv writes, so a caller can tell whether a
+// prior setContainerEnv(containers, name, value) call (or an `oc env` invocation with the same effect) has
+// already taken hold before redoing the work. An empty containers slice is never considered set.
+func containerEnvSet(containers []corev1.Container, name, value string) bool {
+	if len(containers) == 0 {
+		return false
+	}
+	for i := range containers {
+		found := false
+		for _, e := range containers[i].Env {
+			if e.Name == name && e.Value == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// setContainerEnv sets (or replaces) the env var named name to value on every container in containers.
+func setContainerEnv(containers []corev1.Container, name, value string) {
+	for i := range containers {
+		replaced := false
+		for j, e := range containers[i].Env {
+			if e.Name == name {
+				containers[i].Env[j].Value = value
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			containers[i].Env = append(containers[i].Env, corev1.EnvVar{Name: name, Value: value})
+		}
+	}
+}